@@ -0,0 +1,89 @@
+// Command errgen generates httputils.APIError types from error catalogs, along with their
+// Markdown documentation and an OpenAPI components section.
+//
+// Catalogs are YAML or JSON files listing errors with their code, HTTP status, message template
+// and typed params:
+//
+//	errors:
+//	  - code: user_not_found
+//	    status: 404
+//	    message: user {id} not found
+//	    description: The user does not exist or was deleted.
+//	    params:
+//	      - name: id
+//	        type: string
+//
+// Codes must be unique across catalogs. Use it with go:generate:
+//
+//	//go:generate go run github.com/viaduct-ai/vgo/cmd/errgen -package errs -out errors_gen.go -docs ERRORS.md -openapi errors.openapi.yaml errors.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/viaduct-ai/vgo/internal/errgen"
+)
+
+func main() {
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package of the generated Go source, defaults to $GOPACKAGE set by go generate")
+	out := flag.String("out", "errors_gen.go", "output Go source file")
+	docs := flag.String("docs", "", "output Markdown documentation file, if set")
+	openapi := flag.String("openapi", "", "output OpenAPI components file, if set")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: errgen [flags] catalog.yaml...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*pkg, *out, *docs, *openapi, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "errgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(pkg, out, docs, openapi string, catalogs []string) error {
+	if len(catalogs) == 0 {
+		return fmt.Errorf("no catalog files")
+	}
+
+	if pkg == "" {
+		return fmt.Errorf("-package is required outside of go generate")
+	}
+
+	errs, err := errgen.Load(catalogs...)
+	if err != nil {
+		return err
+	}
+
+	src, err := errgen.GenerateGo(pkg, catalogs, errs)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(out, src, 0644); err != nil {
+		return err
+	}
+
+	if docs != "" {
+		if err := ioutil.WriteFile(docs, errgen.GenerateDocs(errs), 0644); err != nil {
+			return err
+		}
+	}
+
+	if openapi != "" {
+		spec, err := errgen.GenerateOpenAPI(errs)
+		if err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(openapi, spec, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "errgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "errors_gen.go")
+	docs := filepath.Join(dir, "ERRORS.md")
+	openapi := filepath.Join(dir, "errors.openapi.yaml")
+
+	if err := run("errs", out, docs, openapi, []string{"testdata/errors.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{out, docs, openapi} {
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		golden := filepath.Join("testdata", filepath.Base(path)+".golden")
+		if *update {
+			if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("want %s to match %s, run go test -update to update it. got\n%s", filepath.Base(path), golden, got)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		pkg      string
+		catalogs []string
+	}{
+		{
+			name: "No Catalogs",
+			pkg:  "errs",
+		},
+		{
+			name:     "No Package",
+			catalogs: []string{"testdata/errors.yaml"},
+		},
+		{
+			name:     "Missing Catalog",
+			pkg:      "errs",
+			catalogs: []string{"testdata/missing.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := run(tt.pkg, filepath.Join(os.TempDir(), "errgen_unused.go"), "", "", tt.catalogs); err == nil {
+				t.Error("want error. got nil")
+			}
+		})
+	}
+}
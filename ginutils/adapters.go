@@ -0,0 +1,200 @@
+package ginutils
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+type adapterContextKey struct{}
+
+// adapterState is the per request state of a wrapped net/http middleware
+type adapterState struct {
+	c      *gin.Context
+	called bool
+}
+
+// WrapMiddleware adapts net/http middleware, such as httputils/middlewares.LoggingMiddleware, to gin.
+//
+// The middleware is constructed once. When it calls the next handler, the rest of the gin chain runs
+// with the request and response writer the middleware passed on, so request context values it adds
+// are visible to gin handlers through c.Request.Context(). If the middleware does not call the next
+// handler, the gin chain is aborted and whatever status the middleware wrote is kept.
+//
+// The middleware must call the next handler before it returns, from the goroutine serving the request,
+// as the rest of the gin chain runs on the *gin.Context, which is not safe for concurrent use and is
+// reused for other requests once the middleware returns. Middleware that times out the next handler,
+// such as http.TimeoutHandler, can't be wrapped.
+func WrapMiddleware(m func(http.Handler) http.Handler) gin.HandlerFunc {
+	handler := m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := r.Context().Value(adapterContextKey{}).(*adapterState)
+		state.called = true
+
+		c := state.c
+		c.Request = r
+
+		writer := c.Writer
+		// comparing interfaces panics if their dynamic type is not comparable, such as a struct with a slice
+		if rw, ok := w.(gin.ResponseWriter); ok && reflect.TypeOf(rw).Comparable() && rw == writer {
+			c.Next()
+			return
+		}
+
+		// the middleware wrapped the response writer, write through it
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK, size: -1}
+		c.Writer = rw
+		c.Next()
+		rw.WriteHeaderNow()
+		c.Writer = writer
+	}))
+
+	return func(c *gin.Context) {
+		state := &adapterState{c: c}
+		ctx := context.WithValue(c.Request.Context(), adapterContextKey{}, state)
+
+		handler.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+
+		if !state.called {
+			c.Abort()
+		}
+	}
+}
+
+// HTTPMiddleware adapts gin middleware to net/http middleware.
+//
+// If the gin middleware calls c.Next, or returns without aborting, next serves the request with
+// c.Request, so request context values the gin middleware sets with c.Request.WithContext are
+// visible to net/http handlers. If it aborts, next is not called.
+func HTTPMiddleware(h gin.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state := &httpAdapterState{h: h, next: next}
+			ctx := context.WithValue(r.Context(), httpAdapterContextKey{}, state)
+
+			httpAdapterEngine().ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+type httpAdapterContextKey struct{}
+
+// httpAdapterState is the per request state of a wrapped gin middleware
+type httpAdapterState struct {
+	h    gin.HandlerFunc
+	next http.Handler
+}
+
+var (
+	httpAdapterOnce sync.Once
+	httpAdapter     *gin.Engine
+)
+
+// httpAdapterEngine returns the engine shared by all the middleware adapted by HTTPMiddleware.
+// Every request is unrouted, so it is served by the NoRoute handlers, which run the gin middleware and
+// then the next handler of the request.
+func httpAdapterEngine() *gin.Engine {
+	httpAdapterOnce.Do(func() {
+		httpAdapter = gin.New()
+		httpAdapter.NoRoute(
+			func(c *gin.Context) {
+				// gin sets the status of unrouted requests to 404 before running the chain
+				c.Status(http.StatusOK)
+
+				c.Request.Context().Value(httpAdapterContextKey{}).(*httpAdapterState).h(c)
+			},
+			func(c *gin.Context) {
+				c.Request.Context().Value(httpAdapterContextKey{}).(*httpAdapterState).next.ServeHTTP(c.Writer, c.Request)
+
+				// the response is complete once next returns, as it is for net/http handlers
+				c.Writer.WriteHeaderNow()
+			},
+		)
+	})
+
+	return httpAdapter
+}
+
+// responseWriter adapts an http.ResponseWriter to a gin.ResponseWriter.
+// Like gin's own writer, WriteHeader only records the status until the body is written or WriteHeaderNow is called.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && w.status != code && !w.Written() {
+		w.status = code
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != -1
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker is not implemented by the underlying http.ResponseWriter")
+	}
+
+	if w.size < 0 {
+		w.size = 0
+	}
+	return h.Hijack()
+}
+
+func (w *responseWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+
+	return make(chan bool)
+}
+
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Pusher() http.Pusher {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package ginutils_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/ginutils"
+	"github.com/viaduct-ai/vgo/httputils/accesslog"
+	"github.com/viaduct-ai/vgo/httputils/middlewares"
+	"github.com/viaduct-ai/vgo/testutils"
+)
+
+type contextKey string
+
+const testKey contextKey = "test"
+
+// statusMiddleware adds a request context value and records the status written through its response writer
+func statusMiddleware(status *int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), testKey, "from net/http")
+			next.ServeHTTP(&recordingWriter{ResponseWriter: w, status: status}, r.WithContext(ctx))
+		})
+	}
+}
+
+type recordingWriter struct {
+	http.ResponseWriter
+	status *int
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	*w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func TestWrapMiddleware(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		handler    gin.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "Body",
+			handler: func(c *gin.Context) {
+				c.String(http.StatusCreated, c.Request.Context().Value(testKey).(string))
+			},
+			wantStatus: http.StatusCreated,
+			wantBody:   "from net/http",
+		},
+		{
+			name: "Status Only",
+			handler: func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			},
+			wantStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status int
+
+			router := gin.New()
+			router.Use(ginutils.WrapMiddleware(statusMiddleware(&status)))
+			router.GET("/test", tt.handler)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("want status code %d. got %d", tt.wantStatus, rr.Code)
+			}
+
+			if status != tt.wantStatus {
+				t.Errorf("want middleware to see status code %d. got %d", tt.wantStatus, status)
+			}
+
+			if rr.Body.String() != tt.wantBody {
+				t.Errorf("want body %q. got %q", tt.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestWrapMiddlewareAbort(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	reject := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+	}
+
+	called := false
+
+	router := gin.New()
+	router.Use(ginutils.WrapMiddleware(reject))
+	router.GET("/test", func(c *gin.Context) {
+		called = true
+	})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	if called {
+		t.Errorf("want handler not to be called")
+	}
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("want status code %d. got %d", http.StatusTooManyRequests, rr.Code)
+	}
+}
+
+// valueWriter is a gin.ResponseWriter that is not comparable
+type valueWriter struct {
+	gin.ResponseWriter
+	tags []string
+}
+
+func TestWrapMiddlewareNonComparableWriter(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	passThrough := func(next http.Handler) http.Handler {
+		return next
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Writer = valueWriter{ResponseWriter: c.Writer}
+	})
+	router.Use(ginutils.WrapMiddleware(passThrough))
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusCreated, "created")
+	})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("want status code %d. got %d", http.StatusCreated, rr.Code)
+	}
+
+	if rr.Body.String() != "created" {
+		t.Errorf("want body %q. got %q", "created", rr.Body.String())
+	}
+}
+
+func TestWrapLoggingMiddleware(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	logger := testutils.NewTestLogger()
+	logging := func(next http.Handler) http.Handler {
+		return middlewares.LoggingMiddleware(logger, next, accesslog.WithResponse(true))
+	}
+
+	router := gin.New()
+	router.Use(ginutils.WrapMiddleware(logging))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusAccepted, gin.H{"test": "test"})
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	if logger.Context["status"] != int64(http.StatusAccepted) {
+		t.Errorf("want logged status %d. got %v", http.StatusAccepted, logger.Context["status"])
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	setValue := func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), testKey, "from gin")
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+
+	authorize := func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}
+
+	tests := []struct {
+		name       string
+		middleware gin.HandlerFunc
+		handler    http.HandlerFunc
+		wantCalled bool
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Context Value",
+			middleware: setValue,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(r.Context().Value(testKey).(string)))
+			},
+			wantCalled: true,
+			wantStatus: http.StatusCreated,
+			wantBody:   "from gin",
+		},
+		{
+			name:       "Implicit Status",
+			middleware: setValue,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+			},
+			wantCalled: true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Middleware Status",
+			middleware: func(c *gin.Context) {
+				c.Status(http.StatusNotFound)
+				c.Next()
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+			},
+			wantCalled: true,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Handler Not Found",
+			middleware: setValue,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+			wantCalled: true,
+			wantStatus: http.StatusNotFound,
+			wantBody:   "404 page not found\n",
+		},
+		{
+			name: "Next Not Called",
+			middleware: func(c *gin.Context) {
+				c.Header("X-Test", "test")
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			},
+			wantCalled: true,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "Abort",
+			middleware: authorize,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				tt.handler(w, r)
+			})
+
+			rr := httptest.NewRecorder()
+			ginutils.HTTPMiddleware(tt.middleware)(handler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if called != tt.wantCalled {
+				t.Errorf("want handler called %t. got %t", tt.wantCalled, called)
+			}
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("want status code %d. got %d", tt.wantStatus, rr.Code)
+			}
+
+			if rr.Body.String() != tt.wantBody {
+				t.Errorf("want body %q. got %q", tt.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Package ginutils contains helpers for gin handlers that mirror the net/http helpers in httputils.
+package ginutils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/viaduct-ai/vgo/httputils"
+)
+
+type validationError struct {
+	msg string
+}
+
+func (e *validationError) Error() string {
+	return e.msg
+}
+
+func (e *validationError) Status() int {
+	return http.StatusBadRequest
+}
+
+func (e *validationError) Message() string {
+	return e.msg
+}
+
+func (e *validationError) Code() string {
+	return "bad_request"
+}
+
+// BindJSON strictly decodes the JSON request body into dst.
+// Unlike gin's ShouldBindJSON, it applies the rules of httputils.DecodeJSONBody and returns the same
+// APIErrors, so gin and net/http services respond identically to malformed requests.
+//
+// dst is then validated with gin's binding.Validator, using the `binding` struct tags.
+// Validation is skipped if binding.Validator is nil. Validation failures are bad request APIErrors.
+func BindJSON(c *gin.Context, dst interface{}) error {
+	if err := httputils.DecodeJSONBody(c.Writer, c.Request, dst); err != nil {
+		return err
+	}
+
+	if binding.Validator == nil {
+		return nil
+	}
+
+	if err := binding.Validator.ValidateStruct(dst); err != nil {
+		return newValidationError(dst, err)
+	}
+
+	return nil
+}
+
+func newValidationError(dst interface{}, err error) error {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return &validationError{msg: fmt.Sprintf("request body is invalid: %v", err)}
+	}
+
+	msgs := make([]string, 0, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		field := jsonFieldPath(reflect.TypeOf(dst), fe.StructNamespace())
+		msgs = append(msgs, fmt.Sprintf("request body field %q failed the %q validation", field, fe.Tag()))
+	}
+
+	return &validationError{msg: strings.Join(msgs, "; ")}
+}
+
+// jsonFieldPath converts the struct namespace of a validation error, such as "User.Addresses[0].ZipCode",
+// to the JSON field path the client sent, such as "addresses[0].zip_code".
+func jsonFieldPath(t reflect.Type, namespace string) string {
+	parts := strings.Split(namespace, ".")
+	// the first part is the name of the top level struct
+	parts = parts[1:]
+
+	for i, part := range parts {
+		name, index := part, ""
+		if j := strings.Index(part, "["); j >= 0 {
+			name, index = part[:j], part[j:]
+		}
+
+		t = elem(t)
+		if t.Kind() != reflect.Struct {
+			continue
+		}
+
+		f, ok := t.FieldByName(name)
+		if !ok {
+			continue
+		}
+
+		t = f.Type
+		if index != "" {
+			// step into the slice or map element
+			t = elem(t).Elem()
+		}
+
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+			parts[i] = tag + index
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
+
+func elem(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
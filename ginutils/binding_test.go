@@ -0,0 +1,119 @@
+package ginutils_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/ginutils"
+	"github.com/viaduct-ai/vgo/httputils"
+)
+
+func TestBindJSON(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	type address struct {
+		ZipCode string `json:"zip_code" binding:"required"`
+	}
+
+	type test struct {
+		Test      string    `json:"test,omitempty" binding:"required"`
+		Addresses []address `json:"addresses" binding:"dive"`
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantBody    test
+		wantStatus  int
+		wantErr     error
+	}{
+		{
+			name:        "Valid",
+			contentType: httputils.ContentTypeJSON,
+			body:        `{"test": "test"}`,
+			wantBody:    test{Test: "test"},
+		},
+		{
+			name:        "Wrong Content-Type",
+			contentType: "text/plain",
+			body:        `{"test": "test"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantErr:     errors.New("Content-Type header is not"),
+		},
+		{
+			name:        "Unknown Field",
+			contentType: httputils.ContentTypeJSON,
+			body:        `{"test": "test", "unknown": "unknown"}`,
+			wantBody:    test{Test: "test"},
+			wantStatus:  http.StatusBadRequest,
+			wantErr:     errors.New("unknown field"),
+		},
+		{
+			name:        "Trailing Data",
+			contentType: httputils.ContentTypeJSON,
+			body:        `{"test": "test"} {"test": "test"}`,
+			wantBody:    test{Test: "test"},
+			wantStatus:  http.StatusBadRequest,
+			wantErr:     errors.New("single JSON object"),
+		},
+		{
+			name:        "Missing Required Field",
+			contentType: httputils.ContentTypeJSON,
+			body:        `{}`,
+			wantStatus:  http.StatusBadRequest,
+			wantErr:     errors.New(`request body field "test" failed the "required" validation`),
+		},
+		{
+			name:        "Nested Field",
+			contentType: httputils.ContentTypeJSON,
+			body:        `{"test": "test", "addresses": [{"zip_code": ""}]}`,
+			wantBody:    test{Test: "test", Addresses: []address{{}}},
+			wantStatus:  http.StatusBadRequest,
+			wantErr:     errors.New(`request body field "addresses[0].zip_code" failed the "required" validation`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rr)
+			c.Request = httptest.NewRequest(http.MethodPost, "/test", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set(httputils.ContentType, tt.contentType)
+
+			var got test
+			err := ginutils.BindJSON(c, &got)
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("want no error. got %v", err)
+			}
+
+			if tt.wantErr != nil {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr.Error()) {
+					t.Errorf("want %v. got %v", tt.wantErr, err)
+				}
+
+				var apiError httputils.APIError
+				if !errors.As(err, &apiError) {
+					t.Fatalf("want an APIError. got %T", err)
+				}
+
+				if apiError.Status() != tt.wantStatus {
+					t.Errorf("want status %d. got %d", tt.wantStatus, apiError.Status())
+				}
+			}
+
+			if !reflect.DeepEqual(tt.wantBody, got) {
+				t.Errorf("want %+v. got %+v", tt.wantBody, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/concurrency"
+)
+
+// ConcurrencyLimitMiddleware limits the number of requests in flight with l.
+// Shed requests are aborted with a 503 concurrency.Error through httputils.ServeRequestError.
+func ConcurrencyLimitMiddleware(l *concurrency.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := l.Admit(c.Writer, c.Request)
+		if err != nil {
+			httputils.ServeRequestError(c.Writer, c.Request, err)
+			c.Abort()
+			return
+		}
+		defer t.Release()
+
+		c.Next() // Pass on to the next-in-chain
+	}
+}
@@ -0,0 +1,51 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/ginutils/middlewares"
+	"github.com/viaduct-ai/vgo/httputils/concurrency"
+)
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	l := concurrency.NewLimiter(concurrency.Config{
+		Limit:  concurrency.FixedLimit(1),
+		Bypass: concurrency.Paths("/health"),
+	})
+
+	router := gin.New()
+	router.Use(middlewares.ConcurrencyLimitMiddleware(l))
+	router.GET("/health", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/test", func(c *gin.Context) {
+		// shed while this request is in flight, except for health checks
+		for path, want := range map[string]int{"/test": http.StatusServiceUnavailable, "/health": http.StatusOK} {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if rr.Code != want {
+				t.Errorf("want status code %d for %s. got %d", want, path, rr.Code)
+			}
+		}
+		c.Status(http.StatusOK)
+	})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("want status code %d. got %d", http.StatusOK, rr.Code)
+	}
+
+	if got := l.Stats().InFlight; got != 0 {
+		t.Errorf("want no requests in flight. got %d", got)
+	}
+}
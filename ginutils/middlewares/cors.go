@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/httputils/cors"
+)
+
+// CORSMiddleware sets the CORS headers configured by cfg and answers preflight requests.
+// It must be added with Use on the engine so preflight requests to routes without an OPTIONS handler reach it.
+func CORSMiddleware(cfg *cors.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.Handle(c.Writer, c.Request) {
+			c.Abort()
+			return
+		}
+
+		c.Next() // Pass on to the next-in-chain
+	}
+}
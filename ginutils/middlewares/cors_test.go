@@ -0,0 +1,77 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/ginutils/middlewares"
+	"github.com/viaduct-ai/vgo/httputils/cors"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middlewares.CORSMiddleware(&cors.Config{
+		AllowedOrigins: []string{"https://app.viaduct.ai"},
+	}))
+	router.POST("/test", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		headers    map[string]string
+		wantStatus int
+		wantOrigin string
+	}{
+		{
+			name:   "Preflight",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://app.viaduct.ai",
+				"Access-Control-Request-Method": http.MethodPost,
+			},
+			wantStatus: http.StatusNoContent,
+			wantOrigin: "https://app.viaduct.ai",
+		},
+		{
+			name:       "Request",
+			method:     http.MethodPost,
+			headers:    map[string]string{"Origin": "https://app.viaduct.ai"},
+			wantStatus: http.StatusCreated,
+			wantOrigin: "https://app.viaduct.ai",
+		},
+		{
+			name:       "Disallowed",
+			method:     http.MethodPost,
+			headers:    map[string]string{"Origin": "https://evil.com"},
+			wantStatus: http.StatusCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("want status code %d. got %d", tt.wantStatus, rr.Code)
+			}
+
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("want origin %q. got %q", tt.wantOrigin, got)
+			}
+		})
+	}
+}
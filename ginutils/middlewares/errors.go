@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/httputils"
+)
+
+// ErrorMiddleware serves the errors handlers attach with c.Error using httputils.ServeRequestError,
+// so gin services respond with the same error format as net/http services, including the request ID
+// set by the RequestID middleware and application/problem+json.
+//
+// The most recent error implementing httputils.APIError is served. If no error implements it,
+// an internal error is served. Nothing is written if the response has already been written.
+func ErrorMiddleware(c *gin.Context) {
+	c.Next() // Pass on to the next-in-chain
+
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+
+	httputils.ServeRequestError(c.Writer, c.Request, mostRelevantError(c.Errors))
+}
+
+// mostRelevantError returns the last APIError of errs, or the last error if there is none
+func mostRelevantError(errs []*gin.Error) error {
+	for i := len(errs) - 1; i >= 0; i-- {
+		var apiError httputils.APIError
+		if errors.As(errs[i].Err, &apiError) {
+			return apiError
+		}
+	}
+
+	return errs[len(errs)-1].Err
+}
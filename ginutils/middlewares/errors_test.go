@@ -0,0 +1,164 @@
+package middlewares_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/ginutils/middlewares"
+	"github.com/viaduct-ai/vgo/httputils"
+)
+
+type testAPIError struct {
+	status int
+}
+
+func (e testAPIError) Error() string {
+	return "test"
+}
+
+func (e testAPIError) Message() string {
+	return "test"
+}
+
+func (e testAPIError) Code() string {
+	return "test"
+}
+
+func (e testAPIError) Status() int {
+	return e.status
+}
+
+func TestErrorMiddleware(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	internalError := httputils.APIErrorResponse{
+		Message: "an internal error has occurred",
+		Code:    "internal",
+	}
+
+	tests := []struct {
+		name       string
+		handler    gin.HandlerFunc
+		wantStatus int
+		wantBody   *httputils.APIErrorResponse
+	}{
+		{
+			name: "No Error",
+			handler: func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "API Error",
+			handler: func(c *gin.Context) {
+				c.Error(testAPIError{status: http.StatusTeapot})
+			},
+			wantStatus: http.StatusTeapot,
+			wantBody: &httputils.APIErrorResponse{
+				Message: "test",
+				Code:    "test",
+			},
+		},
+		{
+			name: "Wrapped API Error Preferred",
+			handler: func(c *gin.Context) {
+				c.Error(testAPIError{status: http.StatusNotFound})
+				c.Error(fmt.Errorf("lookup: %w", testAPIError{status: http.StatusConflict}))
+				c.Error(errors.New("unknown"))
+			},
+			wantStatus: http.StatusConflict,
+			wantBody: &httputils.APIErrorResponse{
+				Message: "test",
+				Code:    "test",
+			},
+		},
+		{
+			name: "Unknown Error",
+			handler: func(c *gin.Context) {
+				c.Error(errors.New("unknown"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   &internalError,
+		},
+		{
+			name: "Already Written",
+			handler: func(c *gin.Context) {
+				c.Error(errors.New("unknown"))
+				c.Status(http.StatusAccepted)
+				c.Writer.WriteHeaderNow()
+			},
+			wantStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(middlewares.ErrorMiddleware)
+			router.GET("/test", tt.handler)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("want status code %d. got %d", tt.wantStatus, rr.Code)
+			}
+
+			if tt.wantBody == nil {
+				return
+			}
+
+			// the body must match httputils.ServeError exactly
+			want := httptest.NewRecorder()
+			httputils.ServeJSON(want, tt.wantStatus, tt.wantBody)
+
+			if rr.Body.String() != want.Body.String() {
+				t.Errorf("want body %s. got %s", want.Body.String(), rr.Body.String())
+			}
+
+			if rr.Header().Get(httputils.ContentType) != httputils.ContentTypeJSON {
+				t.Errorf("want content type %s. got %s", httputils.ContentTypeJSON, rr.Header().Get(httputils.ContentType))
+			}
+		})
+	}
+}
+
+func TestErrorMiddlewareProblemJSON(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		// as set by the RequestID middleware
+		c.Header("X-Request-Id", "abc")
+	}, middlewares.ErrorMiddleware)
+	router.GET("/test", func(c *gin.Context) {
+		c.Error(testAPIError{status: http.StatusTeapot})
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/test", nil)
+	r.Header.Set("Accept", httputils.ContentTypeProblemJSON)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, r)
+
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("want status code %d. got %d", http.StatusTeapot, rr.Code)
+	}
+
+	if got := rr.Header().Get(httputils.ContentType); got != httputils.ContentTypeProblemJSON {
+		t.Errorf("want content type %s. got %s", httputils.ContentTypeProblemJSON, got)
+	}
+
+	want := `{"type":"about:blank","title":"I'm a teapot","status":418,"detail":"test","instance":"/test","code":"test","request_id":"abc"}`
+	if got := rr.Body.String(); got != want {
+		t.Errorf("want body %s. got %s", want, got)
+	}
+}
@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/ginutils"
+	"github.com/viaduct-ai/vgo/httputils/idempotency"
+)
+
+// IdempotencyMiddleware honors the Idempotency-Key header of requests as configured by cfg,
+// replaying the stored response of retried requests
+func IdempotencyMiddleware(cfg *idempotency.Config) gin.HandlerFunc {
+	return ginutils.WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cfg.Serve(w, r, next)
+		})
+	})
+}
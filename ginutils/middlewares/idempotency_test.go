@@ -0,0 +1,77 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/ginutils/middlewares"
+	"github.com/viaduct-ai/vgo/httputils/idempotency"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	calls := 0
+
+	router := gin.New()
+	router.Use(middlewares.IdempotencyMiddleware(&idempotency.Config{
+		// requests of unknown callers are not deduplicated
+		Caller: func(r *http.Request) string { return "alice" },
+	}))
+	router.POST("/test", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "First",
+			body:       `{"name": "thing"}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":1}`,
+		},
+		{
+			name:       "Retry",
+			body:       `{"name": "thing"}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":1}`,
+		},
+		{
+			name:       "Different Body",
+			body:       `{"name": "other"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(tt.body))
+			req.Header.Set("Idempotency-Key", "abc")
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("want status code %d. got %d", tt.wantStatus, rr.Code)
+			}
+
+			if tt.wantBody != "" && rr.Body.String() != tt.wantBody {
+				t.Errorf("want body %s. got %s", tt.wantBody, rr.Body)
+			}
+		})
+	}
+
+	if calls != 1 {
+		t.Errorf("want handler to be called once. got %d", calls)
+	}
+}
@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/httputils/accesslog"
+	httpmiddlewares "github.com/viaduct-ai/vgo/httputils/middlewares"
+	"github.com/viaduct-ai/vgo/log"
+)
+
+// LoggingMiddleware logs all incoming requests and their responses using the internal logger.
+// It is the gin equivalent of the net/http LoggingMiddleware, takes the same accesslog options and logs
+// the same entries, including the request ID set by the RequestID middleware.
+// A response entry with the status, size, latency and errors is logged if enabled with accesslog.WithResponse.
+func LoggingMiddleware(l log.Logger, opts ...accesslog.Option) gin.HandlerFunc {
+	o := accesslog.NewOptions(opts...)
+
+	return func(c *gin.Context) {
+		if o.Skip(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		req := o.NewRequest(c.Request)
+		req.Route = c.FullPath()
+		req.RequestID = httpmiddlewares.RequestIDFromContext(c.Request.Context())
+		log.InfoFields(l, "request", o.Schema.RequestFields(req)...)
+
+		c.Next() // Pass on to the next-in-chain
+
+		if !o.LogResponse {
+			return
+		}
+
+		size := c.Writer.Size()
+		if size < 0 {
+			// nothing was written
+			size = 0
+		}
+
+		resp := &accesslog.Response{
+			Status:  c.Writer.Status(),
+			Size:    size,
+			Latency: time.Since(start),
+			Errors:  c.Errors.Errors(),
+		}
+		log.InfoFields(l, "response", o.Schema.ResponseFields(req, resp)...)
+	}
+}
@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/ratelimit"
+)
+
+// RateLimitMiddleware limits the rate of requests per caller as configured by cfg.
+// Limited requests are aborted with a 429 ratelimit.Error through httputils.ServeRequestError.
+func RateLimitMiddleware(cfg *ratelimit.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := cfg.Check(c.Writer, c.Request); err != nil {
+			httputils.ServeRequestError(c.Writer, c.Request, err)
+			c.Abort()
+			return
+		}
+
+		c.Next() // Pass on to the next-in-chain
+	}
+}
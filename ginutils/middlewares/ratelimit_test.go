@@ -0,0 +1,52 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/ginutils/middlewares"
+	"github.com/viaduct-ai/vgo/httputils/ratelimit"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := ratelimit.NewTokenBucket(ratelimit.PerMinute(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+
+	called := 0
+
+	router := gin.New()
+	router.Use(middlewares.RateLimitMiddleware(&ratelimit.Config{
+		Key:     ratelimit.ByIP,
+		Limiter: limiter,
+	}))
+	router.GET("/test", func(c *gin.Context) {
+		called++
+		c.Status(http.StatusOK)
+	})
+
+	wantStatus := []int{http.StatusOK, http.StatusTooManyRequests}
+	for _, want := range wantStatus {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		if rr.Code != want {
+			t.Errorf("want status code %d. got %d", want, rr.Code)
+		}
+
+		if want == http.StatusTooManyRequests && rr.Header().Get("Retry-After") == "" {
+			t.Errorf("want Retry-After header")
+		}
+	}
+
+	if called != 1 {
+		t.Errorf("want handler to be called once. got %d", called)
+	}
+}
@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/security"
+)
+
+// SecurityHeadersMiddleware sets the security headers configured by cfg, such as security.DefaultConfig()
+func SecurityHeadersMiddleware(cfg *security.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r, err := cfg.Apply(c.Writer, c.Request)
+		if err != nil {
+			httputils.ServeRequestError(c.Writer, c.Request, err)
+			c.Abort()
+			return
+		}
+		c.Request = r
+
+		c.Next() // Pass on to the next-in-chain
+	}
+}
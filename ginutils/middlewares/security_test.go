@@ -0,0 +1,44 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/viaduct-ai/vgo/ginutils/middlewares"
+	"github.com/viaduct-ai/vgo/httputils/csp"
+	"github.com/viaduct-ai/vgo/httputils/security"
+)
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &security.Config{
+		NoSniff: true,
+		CSP:     csp.New().Add(csp.StyleSrc, csp.Self).Nonce(csp.StyleSrc),
+	}
+
+	var nonce string
+
+	router := gin.New()
+	router.Use(middlewares.SecurityHeadersMiddleware(cfg))
+	router.GET("/test", func(c *gin.Context) {
+		nonce = csp.NonceFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	if got := rr.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("want X-Content-Type-Options nosniff. got %s", got)
+	}
+
+	want := "style-src 'self' 'nonce-" + nonce + "'"
+	if nonce == "" || rr.Header().Get("Content-Security-Policy") != want {
+		t.Errorf("want %q. got %q", want, rr.Header().Get("Content-Security-Policy"))
+	}
+}
@@ -0,0 +1,235 @@
+// Package accesslog builds request and response log entries for HTTP access logging middleware.
+//
+// Entries are described by a Schema, so the same request can be logged with the
+// legacy vgo keys, the Elastic Common Schema or OpenTelemetry semantic conventions.
+// The net/http and gin logging middlewares share these options.
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/viaduct-ai/vgo/jwtutils"
+)
+
+// DefaultRedactedFields are the request body fields omitted from logs by default
+var DefaultRedactedFields = []string{"password"}
+
+// DefaultMaxBodySize is the size of the largest request bodies logged by default
+const DefaultMaxBodySize = 8 << 10
+
+// Request is the loggable data of an incoming request
+type Request struct {
+	Method     string
+	Host       string
+	URL        string
+	Path       string
+	Scheme     string
+	Proto      string
+	RemoteAddr string
+	// Route is the route template matched by the router, if known
+	Route string
+	// RequestID is the ID of the request, if known
+	RequestID string
+	Headers   http.Header
+	Query     url.Values
+	// Body is the decoded JSON request body with redacted fields removed.
+	// It is empty for bodies that are not JSON or larger than Options.MaxBodySize.
+	Body map[string]interface{}
+	// Claims are the unverified JWT claims of the request
+	Claims map[string]interface{}
+}
+
+// Subject returns the JWT subject of the request, if any
+func (r *Request) Subject() string {
+	sub, _ := r.Claims["sub"].(string)
+	return sub
+}
+
+// Response is the loggable data of a completed request
+type Response struct {
+	Status  int
+	Size    int
+	Latency time.Duration
+	Errors  []string
+}
+
+// Options configure access logging
+type Options struct {
+	Schema       Schema
+	RedactFields []string
+	SkipPaths    []string
+	LogResponse  bool
+	MaxBodySize  int64
+}
+
+// Option configures Options
+type Option func(*Options)
+
+// WithSchema sets the Schema used to name the logged fields. Defaults to LegacySchema.
+func WithSchema(s Schema) Option {
+	return func(o *Options) {
+		o.Schema = s
+	}
+}
+
+// WithRedactedFields sets the request body fields omitted from logs, at any depth.
+// Fields are matched case-insensitively. Defaults to DefaultRedactedFields.
+func WithRedactedFields(fields ...string) Option {
+	return func(o *Options) {
+		o.RedactFields = fields
+	}
+}
+
+// WithMaxBodySize sets the size of the largest request bodies logged. Larger bodies are streamed
+// to the handler without being logged. Zero disables body logging. Defaults to DefaultMaxBodySize.
+func WithMaxBodySize(n int64) Option {
+	return func(o *Options) {
+		o.MaxBodySize = n
+	}
+}
+
+// WithResponse enables or disables logging a response entry once the request completes. Defaults to disabled.
+func WithResponse(enabled bool) Option {
+	return func(o *Options) {
+		o.LogResponse = enabled
+	}
+}
+
+// WithSkipPaths disables logging for requests to the given paths, such as health checks
+func WithSkipPaths(paths ...string) Option {
+	return func(o *Options) {
+		o.SkipPaths = paths
+	}
+}
+
+// NewOptions applies opts to the default Options
+func NewOptions(opts ...Option) *Options {
+	o := &Options{
+		Schema:       LegacySchema,
+		RedactFields: DefaultRedactedFields,
+		MaxBodySize:  DefaultMaxBodySize,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Skip reports whether requests to path should not be logged
+func (o *Options) Skip(path string) bool {
+	for _, p := range o.SkipPaths {
+		if p == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewRequest reads the loggable data of r.
+// JSON bodies, or bodies without a Content-Type, up to MaxBodySize are read and replaced, so they can
+// still be read by the next handler. Other bodies are not read, and larger ones only up to MaxBodySize
+// with the rest streamed through to the handler.
+func (o *Options) NewRequest(r *http.Request) *Request {
+	// try parse claims from request, ignore errors
+	claims, _ := jwtutils.ParseUnverifiedTokenClaimsFromRequest(r)
+
+	body := map[string]interface{}{}
+
+	if b, ok := o.readBody(r); ok {
+		json.Unmarshal(b, &body)
+		o.redact(body)
+	}
+
+	return &Request{
+		Method:     r.Method,
+		Host:       r.Host,
+		URL:        r.URL.String(),
+		Path:       r.URL.Path,
+		Scheme:     r.URL.Scheme,
+		Proto:      r.Proto,
+		RemoteAddr: r.RemoteAddr,
+		Headers:    r.Header,
+		Query:      r.URL.Query(),
+		Body:       body,
+		Claims:     claims,
+	}
+}
+
+// readBody returns the body of r if it is loggable, replacing it so it can be read again
+func (o *Options) readBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody || o.MaxBodySize <= 0 || r.ContentLength > o.MaxBodySize || !isJSON(r) {
+		return nil, false
+	}
+
+	// read one more byte than the limit to tell whether the body is larger
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, o.MaxBodySize+1))
+	r.Body = &readCloser{
+		Reader: io.MultiReader(bytes.NewReader(b), r.Body),
+		Closer: r.Body,
+	}
+
+	if err != nil || int64(len(b)) > o.MaxBodySize {
+		return nil, false
+	}
+
+	return b, true
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// isJSON reports whether the body of r is JSON, assuming it is if there is no Content-Type
+func isJSON(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// redact removes the redacted fields from v in place
+func (o *Options) redact(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if o.redacted(k) {
+				delete(v, k)
+				continue
+			}
+			o.redact(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			o.redact(child)
+		}
+	}
+}
+
+func (o *Options) redacted(field string) bool {
+	for _, f := range o.RedactFields {
+		if strings.EqualFold(f, field) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,291 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+
+	"github.com/viaduct-ai/vgo/log"
+	"go.uber.org/zap/zapcore"
+)
+
+// Schema names and shapes the fields of access log entries
+type Schema interface {
+	// RequestFields returns the fields of the entry logged when a request is received
+	RequestFields(req *Request) []log.Field
+	// ResponseFields returns the fields of the entry logged when a request completes
+	ResponseFields(req *Request, resp *Response) []log.Field
+}
+
+var (
+	// LegacySchema uses the flat keys vgo has always logged, such as "endpoint", "ip" and "auth"
+	LegacySchema Schema = legacySchema{}
+	// ECSSchema follows the Elastic Common Schema, nesting fields as objects such as http.request.method
+	// https://www.elastic.co/guide/en/ecs/current/ecs-http.html
+	ECSSchema Schema = ecsSchema{}
+	// OTelSchema follows the OpenTelemetry HTTP semantic conventions, using flat dotted attribute keys
+	// https://opentelemetry.io/docs/specs/semconv/http/http-spans/
+	OTelSchema Schema = otelSchema{}
+)
+
+type legacySchema struct{}
+
+func (legacySchema) RequestFields(req *Request) []log.Field {
+	fields := []log.Field{
+		log.String("method", req.Method),
+		log.String("host", req.Host),
+		log.String("url", req.URL),
+		log.String("endpoint", req.Path),
+		log.String("scheme", req.Scheme),
+		log.String("ip", req.RemoteAddr),
+		log.Object("headers", stringsMap(req.Headers)),
+		log.Object("query", stringsMap(req.Query)),
+		log.Any("body", req.Body),
+		log.Any("auth", req.Claims),
+	}
+
+	if req.RequestID != "" {
+		fields = append(fields, log.String("request_id", req.RequestID))
+	}
+
+	return fields
+}
+
+func (legacySchema) ResponseFields(req *Request, resp *Response) []log.Field {
+	fields := []log.Field{
+		log.String("method", req.Method),
+		log.String("endpoint", req.Path),
+		log.Int("status", resp.Status),
+		log.Int("size", resp.Size),
+		log.Duration("latency", resp.Latency),
+	}
+
+	if req.Route != "" {
+		fields = append(fields, log.String("route", req.Route))
+	}
+
+	if req.RequestID != "" {
+		fields = append(fields, log.String("request_id", req.RequestID))
+	}
+
+	if len(resp.Errors) > 0 {
+		fields = append(fields, log.Strings("errors", resp.Errors))
+	}
+
+	return fields
+}
+
+type ecsSchema struct{}
+
+func (ecsSchema) RequestFields(req *Request) []log.Field {
+	fields := []log.Field{
+		log.Object("http", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+			enc.AddString("version", strings.TrimPrefix(req.Proto, "HTTP/"))
+			return enc.AddObject("request", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+				enc.AddString("method", req.Method)
+				if req.RequestID != "" {
+					enc.AddString("id", req.RequestID)
+				}
+				if referrer := req.Headers.Get("Referer"); referrer != "" {
+					enc.AddString("referrer", referrer)
+				}
+				if len(req.Body) > 0 {
+					// body.content is a string in ECS
+					content, err := json.Marshal(req.Body)
+					if err == nil {
+						return enc.AddObject("body", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+							enc.AddString("content", string(content))
+							return nil
+						}))
+					}
+				}
+				return nil
+			}))
+		})),
+		ecsURL(req),
+		log.Object("source", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+			enc.AddString("address", req.RemoteAddr)
+			enc.AddString("ip", host(req.RemoteAddr))
+			return nil
+		})),
+	}
+
+	if ua := req.Headers.Get("User-Agent"); ua != "" {
+		fields = append(fields, log.Object("user_agent", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+			enc.AddString("original", ua)
+			return nil
+		})))
+	}
+
+	if sub := req.Subject(); sub != "" {
+		fields = append(fields, ecsUser(sub))
+	}
+
+	return fields
+}
+
+func (ecsSchema) ResponseFields(req *Request, resp *Response) []log.Field {
+	fields := []log.Field{
+		log.Object("http", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+			enc.AddObject("request", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+				enc.AddString("method", req.Method)
+				if req.RequestID != "" {
+					enc.AddString("id", req.RequestID)
+				}
+				return nil
+			}))
+			if req.Route != "" {
+				enc.AddString("route", req.Route)
+			}
+			return enc.AddObject("response", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+				enc.AddInt("status_code", resp.Status)
+				return enc.AddObject("body", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+					enc.AddInt("bytes", resp.Size)
+					return nil
+				}))
+			}))
+		})),
+		ecsURL(req),
+		log.Object("event", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+			// event.duration is in nanoseconds
+			enc.AddInt64("duration", resp.Latency.Nanoseconds())
+			return nil
+		})),
+	}
+
+	if sub := req.Subject(); sub != "" {
+		fields = append(fields, ecsUser(sub))
+	}
+
+	if len(resp.Errors) > 0 {
+		fields = append(fields, log.Object("error", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+			enc.AddString("message", strings.Join(resp.Errors, "; "))
+			return nil
+		})))
+	}
+
+	return fields
+}
+
+func ecsURL(req *Request) log.Field {
+	return log.Object("url", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+		enc.AddString("original", req.URL)
+		enc.AddString("path", req.Path)
+		if req.Scheme != "" {
+			enc.AddString("scheme", req.Scheme)
+		}
+		enc.AddString("domain", host(req.Host))
+		if len(req.Query) > 0 {
+			enc.AddString("query", req.Query.Encode())
+		}
+		return nil
+	}))
+}
+
+func ecsUser(id string) log.Field {
+	return log.Object("user", log.ObjectMarshalerFunc(func(enc log.ObjectEncoder) error {
+		enc.AddString("id", id)
+		return nil
+	}))
+}
+
+type otelSchema struct{}
+
+func (otelSchema) RequestFields(req *Request) []log.Field {
+	fields := []log.Field{
+		log.String("http.request.method", req.Method),
+		log.String("url.full", req.URL),
+		log.String("url.path", req.Path),
+		log.String("server.address", host(req.Host)),
+		log.String("client.address", host(req.RemoteAddr)),
+		log.String("network.protocol.version", strings.TrimPrefix(req.Proto, "HTTP/")),
+	}
+
+	if req.Scheme != "" {
+		fields = append(fields, log.String("url.scheme", req.Scheme))
+	}
+
+	if len(req.Query) > 0 {
+		fields = append(fields, log.String("url.query", req.Query.Encode()))
+	}
+
+	if req.Route != "" {
+		fields = append(fields, log.String("http.route", req.Route))
+	}
+
+	if ua := req.Headers.Get("User-Agent"); ua != "" {
+		fields = append(fields, log.String("user_agent.original", ua))
+	}
+
+	if sub := req.Subject(); sub != "" {
+		fields = append(fields, log.String("enduser.id", sub))
+	}
+
+	if req.RequestID != "" {
+		fields = append(fields, log.Strings("http.request.header.x-request-id", []string{req.RequestID}))
+	}
+
+	return fields
+}
+
+func (otelSchema) ResponseFields(req *Request, resp *Response) []log.Field {
+	fields := []log.Field{
+		log.String("http.request.method", req.Method),
+		log.String("url.path", req.Path),
+		log.Int("http.response.status_code", resp.Status),
+		log.Int("http.response.body.size", resp.Size),
+		// durations are in seconds in the semantic conventions
+		log.Float64("http.server.request.duration", resp.Latency.Seconds()),
+	}
+
+	if req.Route != "" {
+		fields = append(fields, log.String("http.route", req.Route))
+	}
+
+	if sub := req.Subject(); sub != "" {
+		fields = append(fields, log.String("enduser.id", sub))
+	}
+
+	if req.RequestID != "" {
+		fields = append(fields, log.Strings("http.request.header.x-request-id", []string{req.RequestID}))
+	}
+
+	if len(resp.Errors) > 0 {
+		fields = append(fields, log.String("error.message", strings.Join(resp.Errors, "; ")))
+	}
+
+	return fields
+}
+
+// stringsMap logs multi-valued maps such as http.Header and url.Values as objects of string arrays without reflection
+type stringsMap map[string][]string
+
+func (m stringsMap) MarshalLogObject(enc log.ObjectEncoder) error {
+	for k, v := range m {
+		if err := enc.AddArray(k, stringArray(v)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type stringArray []string
+
+func (a stringArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, v := range a {
+		enc.AppendString(v)
+	}
+
+	return nil
+}
+
+// host strips the port from addr, if any
+func host(addr string) string {
+	h, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return h
+}
@@ -0,0 +1,172 @@
+package accesslog_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils/accesslog"
+	"github.com/viaduct-ai/vgo/log"
+)
+
+func TestSchemas(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "https://api.test.com/v1/users/1?q=test", nil)
+	r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testToken))
+	r.Header.Set("User-Agent", "test")
+
+	req := accesslog.NewOptions().NewRequest(r)
+	req.Route = "/v1/users/:id"
+
+	resp := &accesslog.Response{
+		Status:  http.StatusOK,
+		Size:    10,
+		Latency: time.Second,
+	}
+
+	tests := []struct {
+		name         string
+		schema       accesslog.Schema
+		wantRequest  map[string]interface{}
+		wantResponse map[string]interface{}
+	}{
+		{
+			name:   "Legacy",
+			schema: accesslog.LegacySchema,
+			wantRequest: map[string]interface{}{
+				"method":   http.MethodGet,
+				"host":     "api.test.com",
+				"url":      "https://api.test.com/v1/users/1?q=test",
+				"endpoint": "/v1/users/1",
+				"scheme":   "https",
+				"ip":       "192.0.2.1:1234",
+				"headers":  loggedStrings(req.Headers),
+				"query":    loggedStrings(req.Query),
+				"body":     req.Body,
+				"auth":     req.Claims,
+			},
+			wantResponse: map[string]interface{}{
+				"method":   http.MethodGet,
+				"endpoint": "/v1/users/1",
+				"route":    "/v1/users/:id",
+				"status":   int64(http.StatusOK),
+				"size":     int64(10),
+				"latency":  time.Second,
+			},
+		},
+		{
+			name:   "ECS",
+			schema: accesslog.ECSSchema,
+			wantRequest: map[string]interface{}{
+				"http": map[string]interface{}{
+					"version": "1.1",
+					"request": map[string]interface{}{
+						"method": http.MethodGet,
+					},
+				},
+				"url": map[string]interface{}{
+					"original": "https://api.test.com/v1/users/1?q=test",
+					"path":     "/v1/users/1",
+					"scheme":   "https",
+					"domain":   "api.test.com",
+					"query":    "q=test",
+				},
+				"source": map[string]interface{}{
+					"address": "192.0.2.1:1234",
+					"ip":      "192.0.2.1",
+				},
+				"user_agent": map[string]interface{}{
+					"original": "test",
+				},
+				"user": map[string]interface{}{
+					"id": "1234567890",
+				},
+			},
+			wantResponse: map[string]interface{}{
+				"http": map[string]interface{}{
+					"request": map[string]interface{}{
+						"method": http.MethodGet,
+					},
+					"route": "/v1/users/:id",
+					"response": map[string]interface{}{
+						"status_code": http.StatusOK,
+						"body": map[string]interface{}{
+							"bytes": 10,
+						},
+					},
+				},
+				"url": map[string]interface{}{
+					"original": "https://api.test.com/v1/users/1?q=test",
+					"path":     "/v1/users/1",
+					"scheme":   "https",
+					"domain":   "api.test.com",
+					"query":    "q=test",
+				},
+				"event": map[string]interface{}{
+					"duration": time.Second.Nanoseconds(),
+				},
+				"user": map[string]interface{}{
+					"id": "1234567890",
+				},
+			},
+		},
+		{
+			name:   "OpenTelemetry",
+			schema: accesslog.OTelSchema,
+			wantRequest: map[string]interface{}{
+				"http.request.method":      http.MethodGet,
+				"url.full":                 "https://api.test.com/v1/users/1?q=test",
+				"url.path":                 "/v1/users/1",
+				"url.scheme":               "https",
+				"url.query":                "q=test",
+				"server.address":           "api.test.com",
+				"client.address":           "192.0.2.1",
+				"network.protocol.version": "1.1",
+				"http.route":               "/v1/users/:id",
+				"user_agent.original":      "test",
+				"enduser.id":               "1234567890",
+			},
+			wantResponse: map[string]interface{}{
+				"http.request.method":          http.MethodGet,
+				"url.path":                     "/v1/users/1",
+				"http.route":                   "/v1/users/:id",
+				"http.response.status_code":    int64(http.StatusOK),
+				"http.response.body.size":      int64(10),
+				"http.server.request.duration": float64(1),
+				"enduser.id":                   "1234567890",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRequest := log.FieldsToMap(tt.schema.RequestFields(req)...)
+			if !reflect.DeepEqual(tt.wantRequest, gotRequest) {
+				t.Errorf("want request fields %v. got %v", tt.wantRequest, gotRequest)
+			}
+
+			gotResponse := log.FieldsToMap(tt.schema.ResponseFields(req, resp)...)
+			if !reflect.DeepEqual(tt.wantResponse, gotResponse) {
+				t.Errorf("want response fields %v. got %v", tt.wantResponse, gotResponse)
+			}
+		})
+	}
+}
+
+// loggedStrings is the logged form of multi-valued maps such as http.Header and url.Values
+func loggedStrings(m map[string][]string) map[string]interface{} {
+	logged := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		values := make([]interface{}, len(v))
+		for i, s := range v {
+			values[i] = s
+		}
+		logged[k] = values
+	}
+
+	return logged
+}
@@ -0,0 +1,183 @@
+// Package apierrors provides constructors for the httputils.APIErrors of common cases,
+// so services serve the same status codes and error codes for them.
+//
+// Errors can wrap a cause, carry details and response headers, and an internal message.
+// The cause and internal message are part of Error(), which ServeError logs, but never of
+// Message(), which it serves:
+//
+//	return apierrors.NotFound("user not found").
+//		WithInternal("user %s of org %s", id, org).
+//		WithCause(err)
+//
+// The With methods return copies, so Errors can be declared once and shared, even between goroutines.
+package apierrors
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error is an httputils.APIError also implementing httputils.ErrorDetails and httputils.ErrorHeaders
+type Error struct {
+	status   int
+	code     string
+	msg      string
+	internal string
+	cause    error
+	details  interface{}
+	headers  http.Header
+}
+
+// New returns a reference to a new Error with the given status, code and message
+func New(status int, code, msg string) *Error {
+	return &Error{status: status, code: code, msg: msg}
+}
+
+// NotFound returns a 404 Error with code "not_found"
+func NotFound(msg string) *Error {
+	return New(http.StatusNotFound, "not_found", orDefault(msg, "resource not found"))
+}
+
+// Conflict returns a 409 Error with code "conflict"
+func Conflict(msg string) *Error {
+	return New(http.StatusConflict, "conflict", orDefault(msg, "resource conflict"))
+}
+
+// Unauthorized returns a 401 Error with code "unauthorized"
+func Unauthorized(msg string) *Error {
+	return New(http.StatusUnauthorized, "unauthorized", orDefault(msg, "authentication required"))
+}
+
+// Forbidden returns a 403 Error with code "forbidden"
+func Forbidden(msg string) *Error {
+	return New(http.StatusForbidden, "forbidden", orDefault(msg, "permission denied"))
+}
+
+// Invalid returns a 400 Error with code "invalid", for requests failing validation.
+// Attach the invalid fields with WithDetails.
+func Invalid(msg string) *Error {
+	return New(http.StatusBadRequest, "invalid", orDefault(msg, "invalid request"))
+}
+
+// Unavailable returns a 503 Error with code "unavailable". A positive retryAfter sets the Retry-After header.
+func Unavailable(msg string, retryAfter time.Duration) *Error {
+	e := New(http.StatusServiceUnavailable, "unavailable", orDefault(msg, "service unavailable"))
+	return e.withRetryAfter(retryAfter)
+}
+
+// TooManyRequests returns a 429 Error with code "rate_limited". A positive retryAfter sets the Retry-After header.
+func TooManyRequests(msg string, retryAfter time.Duration) *Error {
+	e := New(http.StatusTooManyRequests, "rate_limited", orDefault(msg, "too many requests"))
+	return e.withRetryAfter(retryAfter)
+}
+
+func orDefault(msg, def string) string {
+	if msg == "" {
+		return def
+	}
+	return msg
+}
+
+func (e *Error) withRetryAfter(d time.Duration) *Error {
+	if d <= 0 {
+		return e
+	}
+
+	// round up to whole seconds
+	secs := int64((d + time.Second - 1) / time.Second)
+	return e.WithHeader("Retry-After", strconv.FormatInt(secs, 10))
+}
+
+// clone returns a shallow copy of e, with a copy of its headers
+func (e *Error) clone() *Error {
+	c := *e
+	c.headers = e.headers.Clone()
+	return &c
+}
+
+// WithCause returns a copy of e caused by err, which errors.Is and errors.As find through it
+func (e *Error) WithCause(err error) *Error {
+	c := e.clone()
+	c.cause = err
+	return c
+}
+
+// WithInternal returns a copy of e with a message logged but never served, formatted with fmt.Sprintf
+func (e *Error) WithInternal(format string, args ...interface{}) *Error {
+	c := e.clone()
+	c.internal = fmt.Sprintf(format, args...)
+	return c
+}
+
+// WithDetails returns a copy of e with details served along with its message. They are marshaled to JSON.
+func (e *Error) WithDetails(details interface{}) *Error {
+	c := e.clone()
+	c.details = details
+	return c
+}
+
+// WithHeader returns a copy of e adding a header to the response serving it
+func (e *Error) WithHeader(key, value string) *Error {
+	c := e.clone()
+	if c.headers == nil {
+		c.headers = http.Header{}
+	}
+	c.headers.Add(key, value)
+	return c
+}
+
+// Error returns the code and message of e, followed by its internal message and cause if any
+func (e *Error) Error() string {
+	s := e.code + ": " + e.msg
+	if e.internal != "" {
+		s += ": " + e.internal
+	}
+	if e.cause != nil {
+		s += ": " + e.cause.Error()
+	}
+	return s
+}
+
+// Status returns the HTTP status of e
+func (e *Error) Status() int {
+	return e.status
+}
+
+// Message returns the message served to clients
+func (e *Error) Message() string {
+	return e.msg
+}
+
+// Code returns the error code of e
+func (e *Error) Code() string {
+	return e.code
+}
+
+// Details returns the details of e, or nil
+func (e *Error) Details() interface{} {
+	return e.details
+}
+
+// Headers returns the response headers of e, or nil
+func (e *Error) Headers() http.Header {
+	return e.headers
+}
+
+// Internal returns the internal message of e
+func (e *Error) Internal() string {
+	return e.internal
+}
+
+// Unwrap returns the cause of e
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is an *Error with the same status and code,
+// so errors.Is(err, apierrors.NotFound("")) matches any NotFound error
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.status == e.status && t.code == e.code
+}
@@ -0,0 +1,153 @@
+package apierrors_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/apierrors"
+)
+
+func TestConstructors(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            *apierrors.Error
+		wantStatus     int
+		wantCode       string
+		wantMsg        string
+		wantRetryAfter string
+	}{
+		{name: "not found", err: apierrors.NotFound(""), wantStatus: http.StatusNotFound, wantCode: "not_found", wantMsg: "resource not found"},
+		{name: "conflict", err: apierrors.Conflict("email taken"), wantStatus: http.StatusConflict, wantCode: "conflict", wantMsg: "email taken"},
+		{name: "unauthorized", err: apierrors.Unauthorized(""), wantStatus: http.StatusUnauthorized, wantCode: "unauthorized", wantMsg: "authentication required"},
+		{name: "forbidden", err: apierrors.Forbidden(""), wantStatus: http.StatusForbidden, wantCode: "forbidden", wantMsg: "permission denied"},
+		{name: "invalid", err: apierrors.Invalid(""), wantStatus: http.StatusBadRequest, wantCode: "invalid", wantMsg: "invalid request"},
+		{name: "unavailable", err: apierrors.Unavailable("", 0), wantStatus: http.StatusServiceUnavailable, wantCode: "unavailable", wantMsg: "service unavailable"},
+		{name: "too many requests", err: apierrors.TooManyRequests("", 30*time.Second), wantStatus: http.StatusTooManyRequests, wantCode: "rate_limited", wantMsg: "too many requests", wantRetryAfter: "30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apiError httputils.APIError = tt.err
+
+			if apiError.Status() != tt.wantStatus {
+				t.Errorf("want status %d. got %d", tt.wantStatus, apiError.Status())
+			}
+
+			if apiError.Code() != tt.wantCode {
+				t.Errorf("want code %s. got %s", tt.wantCode, apiError.Code())
+			}
+
+			if apiError.Message() != tt.wantMsg {
+				t.Errorf("want message %s. got %s", tt.wantMsg, apiError.Message())
+			}
+
+			if got := tt.err.Headers().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("want Retry-After %q. got %q", tt.wantRetryAfter, got)
+			}
+		})
+	}
+}
+
+func TestWrapping(t *testing.T) {
+	err := apierrors.NotFound("user not found").
+		WithInternal("user %d", 42).
+		WithCause(sql.ErrNoRows)
+
+	wrapped := fmt.Errorf("get user: %w", err)
+
+	if !errors.Is(wrapped, sql.ErrNoRows) {
+		t.Errorf("want errors.Is to find the cause")
+	}
+
+	if !errors.Is(wrapped, apierrors.NotFound("")) {
+		t.Errorf("want errors.Is to match NotFound")
+	}
+
+	if errors.Is(wrapped, apierrors.Conflict("")) {
+		t.Errorf("want errors.Is not to match Conflict")
+	}
+
+	var apiErr *apierrors.Error
+	if !errors.As(wrapped, &apiErr) {
+		t.Fatalf("want errors.As to find the *apierrors.Error")
+	}
+
+	want := "not_found: user not found: user 42: sql: no rows in result set"
+	if apiErr.Error() != want {
+		t.Errorf("want error %s. got %s", want, apiErr.Error())
+	}
+
+	if apiErr.Message() != "user not found" {
+		t.Errorf("want internal message and cause not to be in the message. got %s", apiErr.Message())
+	}
+
+	if apiErr.Internal() != "user 42" {
+		t.Errorf("want internal message user 42. got %s", apiErr.Internal())
+	}
+}
+
+func TestDetailsAndHeaders(t *testing.T) {
+	details := map[string]string{"email": "is required"}
+
+	err := apierrors.Invalid("invalid user").
+		WithDetails(details).
+		WithHeader("Warning", "199 - a").
+		WithHeader("Warning", "199 - b")
+
+	if got := err.Details().(map[string]string); got["email"] != "is required" {
+		t.Errorf("want details %v. got %v", details, got)
+	}
+
+	if got := err.Headers()["Warning"]; len(got) != 2 {
+		t.Errorf("want 2 Warning headers. got %v", got)
+	}
+
+	var _ httputils.ErrorDetails = err
+	var _ httputils.ErrorHeaders = err
+}
+
+var errQuotaExceeded = apierrors.TooManyRequests("quota exceeded", time.Minute)
+
+func TestWithCopies(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		i := i
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			err := errQuotaExceeded.
+				WithCause(fmt.Errorf("request %d", i)).
+				WithInternal("request %d", i).
+				WithDetails(i).
+				WithHeader("X-Request", strconv.Itoa(i))
+
+			if got := err.Details(); got != i {
+				t.Errorf("want details %d. got %v", i, got)
+			}
+
+			if got := err.Headers()["X-Request"]; len(got) != 1 || got[0] != strconv.Itoa(i) {
+				t.Errorf("want X-Request header %d. got %v", i, got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if errQuotaExceeded.Unwrap() != nil || errQuotaExceeded.Internal() != "" || errQuotaExceeded.Details() != nil {
+		t.Errorf("want the shared error to be unchanged. got %v", errQuotaExceeded)
+	}
+
+	if got := errQuotaExceeded.Headers(); len(got) != 1 || got.Get("Retry-After") != "60" {
+		t.Errorf("want the shared error headers to be unchanged. got %v", got)
+	}
+}
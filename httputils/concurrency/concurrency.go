@@ -0,0 +1,340 @@
+// Package concurrency sheds load by limiting the number of requests in flight.
+//
+// A Limiter admits requests up to the value of its Limit, which is either fixed or
+// adapts to the observed latency (AIMDLimit, GradientLimit). Requests over the limit wait
+// in a short, bounded queue ordered by Priority and are rejected with a 503 Error once
+// it is full or they have waited too long. Requests matching Config.Bypass, such as
+// health checks, are never limited.
+package concurrency
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	retryAfter = http.CanonicalHeaderKey("Retry-After")
+)
+
+// Priority orders queued requests. Higher priority requests are admitted first
+// and may take the place of lower priority requests in a full queue.
+type Priority int
+
+// Priorities
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+
+	numPriorities = int(PriorityHigh) + 1
+)
+
+// Error is the APIError returned for shed requests
+type Error struct {
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("concurrency limit exceeded, retry after %s", e.RetryAfter)
+}
+
+// Status is 503 Service Unavailable
+func (e *Error) Status() int {
+	return http.StatusServiceUnavailable
+}
+
+// Message tells the caller when to retry
+func (e *Error) Message() string {
+	return fmt.Sprintf("service overloaded, retry after %d seconds", seconds(e.RetryAfter))
+}
+
+// Code is "overloaded"
+func (e *Error) Code() string {
+	return "overloaded"
+}
+
+// Config configures a Limiter
+type Config struct {
+	// Limit is the number of requests allowed in flight. Defaults to FixedLimit(100).
+	Limit Limit
+	// MaxQueue is the number of requests waiting for a slot before requests are rejected.
+	// Zero rejects requests over the limit immediately.
+	MaxQueue int
+	// QueueTimeout is how long a request waits in the queue. Defaults to 100ms.
+	QueueTimeout time.Duration
+	// RetryAfter is suggested to rejected callers. Defaults to 1s.
+	RetryAfter time.Duration
+	// Priority returns the priority of a request. Defaults to PriorityNormal for all requests.
+	Priority func(r *http.Request) Priority
+	// Bypass returns true for requests that are never limited nor counted, such as health checks
+	Bypass func(r *http.Request) bool
+}
+
+// Stats is a snapshot of the state of a Limiter
+type Stats struct {
+	Limit    int
+	InFlight int
+	Queued   int
+	// Accepted and Rejected count requests since the Limiter was created. Bypassed requests are not counted.
+	Accepted uint64
+	Rejected uint64
+}
+
+type waiter struct {
+	priority Priority
+	ready    chan struct{}
+	// admitted is set, under the Limiter lock, when the waiter leaves the queue with a slot
+	admitted bool
+	elem     *list.Element
+}
+
+// Limiter limits the number of requests in flight
+type Limiter struct {
+	cfg Config
+
+	mu       sync.Mutex
+	inflight int
+	queued   int
+	queues   [numPriorities]*list.List
+	accepted uint64
+	rejected uint64
+}
+
+// NewLimiter returns a reference to a new Limiter
+func NewLimiter(cfg Config) *Limiter {
+	if cfg.Limit == nil {
+		cfg.Limit = FixedLimit(100)
+	}
+	if cfg.QueueTimeout <= 0 {
+		cfg.QueueTimeout = 100 * time.Millisecond
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = time.Second
+	}
+
+	l := &Limiter{cfg: cfg}
+	for i := range l.queues {
+		l.queues[i] = list.New()
+	}
+
+	return l
+}
+
+// Stats returns the current limit, requests in flight and queued, and the counts of
+// accepted and rejected requests, for example to export them as metrics
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return Stats{
+		Limit:    l.cfg.Limit.Limit(),
+		InFlight: l.inflight,
+		Queued:   l.queued,
+		Accepted: l.accepted,
+		Rejected: l.rejected,
+	}
+}
+
+// Admit acquires a slot for r, unless it bypasses the limit.
+// It sets Retry-After on w and returns an *Error if the request is rejected.
+// The returned Token must be released once the request completes; it is nil for bypassed requests.
+func (l *Limiter) Admit(w http.ResponseWriter, r *http.Request) (*Token, error) {
+	if l.cfg.Bypass != nil && l.cfg.Bypass(r) {
+		return nil, nil
+	}
+
+	p := PriorityNormal
+	if l.cfg.Priority != nil {
+		p = l.cfg.Priority(r)
+	}
+
+	t, err := l.Acquire(r.Context(), p)
+	if err != nil {
+		w.Header().Set(retryAfter, strconv.FormatInt(seconds(l.cfg.RetryAfter), 10))
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Acquire waits for a slot for a request of priority p.
+// It returns an *Error if the queue is full or the wait times out, and ctx.Err() if ctx is done.
+func (l *Limiter) Acquire(ctx context.Context, p Priority) (*Token, error) {
+	if p < PriorityLow {
+		p = PriorityLow
+	} else if p > PriorityHigh {
+		p = PriorityHigh
+	}
+
+	l.mu.Lock()
+
+	if l.inflight < l.cfg.Limit.Limit() && l.queued == 0 {
+		l.inflight++
+		l.accepted++
+		l.mu.Unlock()
+		return l.newToken(ctx), nil
+	}
+
+	if l.queued >= l.cfg.MaxQueue && !l.evict(p) {
+		l.rejected++
+		l.mu.Unlock()
+		return nil, l.error()
+	}
+
+	wt := &waiter{priority: p, ready: make(chan struct{})}
+	wt.elem = l.queues[p].PushBack(wt)
+	l.queued++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.cfg.QueueTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-wt.ready:
+	case <-timer.C:
+		err = l.error()
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if wt.admitted {
+		if err != nil {
+			// admitted while giving up, hand the slot over
+			l.inflight--
+			l.accepted--
+			l.rejected++
+			l.admit()
+			return nil, err
+		}
+
+		return l.newToken(ctx), nil
+	}
+
+	if wt.elem != nil {
+		// timed out or canceled in the queue
+		l.queues[wt.priority].Remove(wt.elem)
+		wt.elem = nil
+		l.queued--
+		l.rejected++
+		return nil, err
+	}
+
+	// evicted by a higher priority request
+	return nil, l.error()
+}
+
+// evict drops the newest of the lowest priority waiters, if lower than p, to make room for p.
+// l.mu must be held.
+func (l *Limiter) evict(p Priority) bool {
+	for i := PriorityLow; i < p; i++ {
+		if e := l.queues[i].Back(); e != nil {
+			wt := l.queues[i].Remove(e).(*waiter)
+			wt.elem = nil
+			l.queued--
+			l.rejected++
+			close(wt.ready)
+			return true
+		}
+	}
+
+	return false
+}
+
+// admit moves the highest priority waiters in flight while the limit allows. l.mu must be held.
+func (l *Limiter) admit() {
+	for l.queued > 0 && l.inflight < l.cfg.Limit.Limit() {
+		for i := numPriorities - 1; i >= 0; i-- {
+			if e := l.queues[i].Front(); e != nil {
+				wt := l.queues[i].Remove(e).(*waiter)
+				wt.elem = nil
+				wt.admitted = true
+				l.queued--
+				l.inflight++
+				l.accepted++
+				close(wt.ready)
+				break
+			}
+		}
+	}
+}
+
+func (l *Limiter) release(t *Token, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cfg.Limit.Update(time.Since(t.start), l.inflight, dropped)
+	l.inflight--
+	l.admit()
+}
+
+func (l *Limiter) newToken(ctx context.Context) *Token {
+	return &Token{
+		limiter: l,
+		ctx:     ctx,
+		start:   time.Now(),
+	}
+}
+
+func (l *Limiter) error() error {
+	return &Error{RetryAfter: l.cfg.RetryAfter}
+}
+
+// Token is a slot acquired from a Limiter
+type Token struct {
+	limiter *Limiter
+	ctx     context.Context
+	start   time.Time
+	once    sync.Once
+}
+
+// Release returns the slot to the Limiter, reporting the latency of the request to its Limit.
+// A request whose context deadline was exceeded is reported as dropped.
+// Release can be called on a nil Token and more than once.
+func (t *Token) Release() {
+	if t == nil {
+		return
+	}
+
+	t.once.Do(func() {
+		t.limiter.release(t, t.ctx.Err() == context.DeadlineExceeded)
+	})
+}
+
+// Drop returns the slot to the Limiter, reporting the request as dropped so an adaptive Limit decreases.
+// Drop can be called on a nil Token and more than once.
+func (t *Token) Drop() {
+	if t == nil {
+		return
+	}
+
+	t.once.Do(func() {
+		t.limiter.release(t, true)
+	})
+}
+
+// Paths returns a function matching requests to any of paths, to be used as Config.Bypass
+func Paths(paths ...string) func(r *http.Request) bool {
+	set := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		set[p] = true
+	}
+
+	return func(r *http.Request) bool {
+		return set[r.URL.Path]
+	}
+}
+
+// seconds rounds d up to whole seconds
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,168 @@
+package concurrency_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/concurrency"
+)
+
+func TestLimiterAcquire(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := concurrency.NewLimiter(concurrency.Config{
+		Limit:        concurrency.FixedLimit(1),
+		MaxQueue:     1,
+		QueueTimeout: time.Second,
+	})
+
+	first, err := l.Acquire(ctx, concurrency.PriorityNormal)
+	if err != nil {
+		t.Fatalf("want first request to be accepted. got %v", err)
+	}
+
+	queued := make(chan error)
+	go func() {
+		tok, err := l.Acquire(ctx, concurrency.PriorityNormal)
+		tok.Release()
+		queued <- err
+	}()
+
+	waitFor(t, func() bool { return l.Stats().Queued == 1 })
+
+	// the queue is full
+	_, err = l.Acquire(ctx, concurrency.PriorityNormal)
+
+	var apiError httputils.APIError
+	if !errors.As(err, &apiError) || apiError.Status() != http.StatusServiceUnavailable || apiError.Code() != "overloaded" {
+		t.Fatalf("want a 503 APIError. got %v", err)
+	}
+
+	first.Release()
+
+	if err := <-queued; err != nil {
+		t.Errorf("want queued request to be accepted. got %v", err)
+	}
+
+	want := concurrency.Stats{Limit: 1, Accepted: 2, Rejected: 1}
+	if got := l.Stats(); got != want {
+		t.Errorf("want stats %+v. got %+v", want, got)
+	}
+}
+
+func TestLimiterPriority(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := concurrency.NewLimiter(concurrency.Config{
+		Limit:        concurrency.FixedLimit(1),
+		MaxQueue:     1,
+		QueueTimeout: time.Second,
+	})
+
+	first, _ := l.Acquire(ctx, concurrency.PriorityNormal)
+
+	low := make(chan error)
+	go func() {
+		_, err := l.Acquire(ctx, concurrency.PriorityLow)
+		low <- err
+	}()
+
+	waitFor(t, func() bool { return l.Stats().Queued == 1 })
+
+	high := make(chan error)
+	go func() {
+		tok, err := l.Acquire(ctx, concurrency.PriorityHigh)
+		tok.Release()
+		high <- err
+	}()
+
+	if err := <-low; err == nil {
+		t.Errorf("want low priority request to be evicted from the queue")
+	}
+
+	first.Release()
+
+	if err := <-high; err != nil {
+		t.Errorf("want high priority request to be accepted. got %v", err)
+	}
+}
+
+func TestLimiterQueueTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := concurrency.NewLimiter(concurrency.Config{
+		Limit:        concurrency.FixedLimit(1),
+		MaxQueue:     10,
+		QueueTimeout: 10 * time.Millisecond,
+	})
+
+	first, _ := l.Acquire(ctx, concurrency.PriorityNormal)
+	defer first.Release()
+
+	var apiError httputils.APIError
+	if _, err := l.Acquire(ctx, concurrency.PriorityNormal); !errors.As(err, &apiError) {
+		t.Errorf("want queued request to time out with an APIError. got %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if _, err := l.Acquire(canceled, concurrency.PriorityNormal); err != context.Canceled {
+		t.Errorf("want %v. got %v", context.Canceled, err)
+	}
+
+	if got := l.Stats().Queued; got != 0 {
+		t.Errorf("want empty queue. got %d", got)
+	}
+}
+
+func TestLimiterAdmit(t *testing.T) {
+	t.Parallel()
+
+	l := concurrency.NewLimiter(concurrency.Config{
+		Limit:      concurrency.FixedLimit(1),
+		RetryAfter: 2 * time.Second,
+		Bypass:     concurrency.Paths("/health"),
+	})
+
+	first, err := l.Admit(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatalf("want first request to be accepted. got %v", err)
+	}
+	defer first.Release()
+
+	rr := httptest.NewRecorder()
+	if _, err := l.Admit(rr, httptest.NewRequest(http.MethodGet, "/", nil)); err == nil {
+		t.Errorf("want second request to be rejected")
+	}
+
+	if got := rr.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("want Retry-After 2. got %s", got)
+	}
+
+	health, err := l.Admit(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	if err != nil || health != nil {
+		t.Errorf("want health check to bypass the limit. got %v, %v", health, err)
+	}
+}
+
+// waitFor polls cond until it is true or a second has passed
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("want condition to be met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,193 @@
+package concurrency
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit decides how many requests may be in flight at once
+type Limit interface {
+	// Limit returns the current limit
+	Limit() int
+	// Update adjusts the limit after a request completes in rtt with inflight requests in flight.
+	// dropped is set if the request timed out or was otherwise a sign of overload.
+	Update(rtt time.Duration, inflight int, dropped bool)
+}
+
+// FixedLimit is a Limit that never changes
+type FixedLimit int
+
+// Limit returns l
+func (l FixedLimit) Limit() int {
+	return int(l)
+}
+
+// Update does nothing
+func (l FixedLimit) Update(rtt time.Duration, inflight int, dropped bool) {}
+
+// AIMDConfig configures an AIMDLimit
+type AIMDConfig struct {
+	// Initial is the starting limit. Defaults to 20.
+	Initial int
+	// Min and Max bound the limit. They default to 1 and 1000.
+	Min int
+	Max int
+	// Backoff is the ratio the limit is multiplied by on overload. Defaults to 0.9.
+	Backoff float64
+	// Timeout is the latency above which a request is a sign of overload. Zero disables it.
+	Timeout time.Duration
+}
+
+// AIMDLimit is an additive increase, multiplicative decrease Limit.
+// The limit grows by one for every request completing while the limit is in use,
+// and is multiplied by Backoff when a request is dropped or slower than Timeout.
+type AIMDLimit struct {
+	cfg AIMDConfig
+
+	mu    sync.Mutex
+	limit int
+}
+
+// NewAIMDLimit returns a reference to a new AIMDLimit
+func NewAIMDLimit(cfg AIMDConfig) *AIMDLimit {
+	if cfg.Initial <= 0 {
+		cfg.Initial = 20
+	}
+	if cfg.Min <= 0 {
+		cfg.Min = 1
+	}
+	if cfg.Max <= 0 {
+		cfg.Max = 1000
+	}
+	if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+		cfg.Backoff = 0.9
+	}
+
+	return &AIMDLimit{
+		cfg:   cfg,
+		limit: clamp(cfg.Initial, cfg.Min, cfg.Max),
+	}
+}
+
+// Limit returns the current limit
+func (l *AIMDLimit) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.limit
+}
+
+// Update adjusts the limit
+func (l *AIMDLimit) Update(rtt time.Duration, inflight int, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case dropped || (l.cfg.Timeout > 0 && rtt > l.cfg.Timeout):
+		l.limit = int(float64(l.limit) * l.cfg.Backoff)
+	case inflight*2 >= l.limit:
+		// only grow while at least half of the limit is used, so idle periods do not inflate it
+		l.limit++
+	}
+
+	l.limit = clamp(l.limit, l.cfg.Min, l.cfg.Max)
+}
+
+// GradientConfig configures a GradientLimit
+type GradientConfig struct {
+	// Initial is the starting limit. Defaults to 20.
+	Initial int
+	// Min and Max bound the limit. They default to 1 and 1000.
+	Min int
+	Max int
+	// Smoothing is the weight of a new limit estimate, between 0 and 1. Defaults to 0.2.
+	Smoothing float64
+	// Tolerance is the ratio of latency increase tolerated before the limit decreases. Defaults to 1.5.
+	Tolerance float64
+}
+
+// GradientLimit is a Limit following the gradient between the long term average latency
+// and the latency of the latest request: the limit decreases when latency rises above
+// the average, as requests start to queue, and increases when it is stable.
+type GradientLimit struct {
+	cfg GradientConfig
+
+	mu      sync.Mutex
+	limit   float64
+	longRTT float64
+}
+
+// NewGradientLimit returns a reference to a new GradientLimit
+func NewGradientLimit(cfg GradientConfig) *GradientLimit {
+	if cfg.Initial <= 0 {
+		cfg.Initial = 20
+	}
+	if cfg.Min <= 0 {
+		cfg.Min = 1
+	}
+	if cfg.Max <= 0 {
+		cfg.Max = 1000
+	}
+	if cfg.Smoothing <= 0 || cfg.Smoothing > 1 {
+		cfg.Smoothing = 0.2
+	}
+	if cfg.Tolerance < 1 {
+		cfg.Tolerance = 1.5
+	}
+
+	return &GradientLimit{
+		cfg:   cfg,
+		limit: float64(clamp(cfg.Initial, cfg.Min, cfg.Max)),
+	}
+}
+
+// Limit returns the current limit
+func (l *GradientLimit) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// Update adjusts the limit
+func (l *GradientLimit) Update(rtt time.Duration, inflight int, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sample := float64(rtt)
+	if sample <= 0 {
+		return
+	}
+
+	if l.longRTT == 0 {
+		l.longRTT = sample
+	}
+	// exponentially weighted average over roughly the last 100 requests
+	l.longRTT = l.longRTT*0.99 + sample*0.01
+
+	// don't grow the limit while it is not used
+	if !dropped && float64(inflight) < l.limit/2 {
+		return
+	}
+
+	gradient := 0.5
+	if !dropped {
+		gradient = math.Max(0.5, math.Min(1, l.cfg.Tolerance*l.longRTT/sample))
+	}
+
+	// allow a queue of sqrt(limit) requests, so the limit can grow when latency is stable
+	estimate := l.limit*gradient + math.Sqrt(l.limit)
+	l.limit = l.limit*(1-l.cfg.Smoothing) + estimate*l.cfg.Smoothing
+	l.limit = math.Max(float64(l.cfg.Min), math.Min(float64(l.cfg.Max), l.limit))
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
@@ -0,0 +1,87 @@
+package concurrency_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils/concurrency"
+)
+
+func TestAIMDLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		rtt       time.Duration
+		inflight  int
+		dropped   bool
+		wantLimit int
+	}{
+		{
+			name:      "Increase",
+			rtt:       time.Millisecond,
+			inflight:  10,
+			wantLimit: 11,
+		},
+		{
+			name:      "Idle",
+			rtt:       time.Millisecond,
+			inflight:  1,
+			wantLimit: 10,
+		},
+		{
+			name:      "Dropped",
+			rtt:       time.Millisecond,
+			inflight:  10,
+			dropped:   true,
+			wantLimit: 5,
+		},
+		{
+			name:      "Timeout",
+			rtt:       time.Second,
+			inflight:  10,
+			wantLimit: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := concurrency.NewAIMDLimit(concurrency.AIMDConfig{
+				Initial: 10,
+				Backoff: 0.5,
+				Timeout: 100 * time.Millisecond,
+			})
+
+			l.Update(tt.rtt, tt.inflight, tt.dropped)
+
+			if got := l.Limit(); got != tt.wantLimit {
+				t.Errorf("want limit %d. got %d", tt.wantLimit, got)
+			}
+		})
+	}
+}
+
+func TestGradientLimit(t *testing.T) {
+	t.Parallel()
+
+	l := concurrency.NewGradientLimit(concurrency.GradientConfig{Initial: 20, Max: 50})
+
+	// stable latency grows the limit
+	for i := 0; i < 100; i++ {
+		l.Update(10*time.Millisecond, l.Limit(), false)
+	}
+
+	stable := l.Limit()
+	if stable <= 20 {
+		t.Fatalf("want limit to grow above 20. got %d", stable)
+	}
+
+	// rising latency shrinks it
+	for i := 0; i < 20; i++ {
+		l.Update(100*time.Millisecond, l.Limit(), false)
+	}
+
+	if got := l.Limit(); got >= stable {
+		t.Errorf("want limit to shrink below %d. got %d", stable, got)
+	}
+}
@@ -0,0 +1,236 @@
+// Package cors implements Cross-Origin Resource Sharing.
+//
+// A Config lists the origins, methods and headers allowed to make cross-origin requests.
+// The net/http and gin CORS middlewares both use a Config.
+// https://fetch.spec.whatwg.org/#http-cors-protocol
+package cors
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/viaduct-ai/vgo/log"
+)
+
+var (
+	origin           = http.CanonicalHeaderKey("Origin")
+	vary             = http.CanonicalHeaderKey("Vary")
+	requestMethod    = http.CanonicalHeaderKey("Access-Control-Request-Method")
+	requestHeaders   = http.CanonicalHeaderKey("Access-Control-Request-Headers")
+	allowOrigin      = http.CanonicalHeaderKey("Access-Control-Allow-Origin")
+	allowMethods     = http.CanonicalHeaderKey("Access-Control-Allow-Methods")
+	allowHeaders     = http.CanonicalHeaderKey("Access-Control-Allow-Headers")
+	allowCredentials = http.CanonicalHeaderKey("Access-Control-Allow-Credentials")
+	exposeHeaders    = http.CanonicalHeaderKey("Access-Control-Expose-Headers")
+	maxAge           = http.CanonicalHeaderKey("Access-Control-Max-Age")
+)
+
+var (
+	// DefaultAllowedMethods are allowed if Config.AllowedMethods is empty
+	DefaultAllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	// DefaultAllowedHeaders are allowed if Config.AllowedHeaders is empty
+	DefaultAllowedHeaders = []string{"Accept", "Authorization", "Content-Type", "X-Request-Id"}
+)
+
+// Config configures CORS middleware
+type Config struct {
+	// AllowedOrigins are matched exactly, such as "https://app.viaduct.ai".
+	// A "*" in place of subdomains matches any subdomain, such as "https://*.viaduct.ai",
+	// and "*" alone allows any origin.
+	AllowedOrigins []string
+	// AllowedOriginPatterns are matched against the whole origin
+	AllowedOriginPatterns []*regexp.Regexp
+	// AllowOriginFunc allows the origins it returns true for
+	AllowOriginFunc func(origin string) bool
+	// AllowedMethods defaults to DefaultAllowedMethods
+	AllowedMethods []string
+	// AllowedHeaders defaults to DefaultAllowedHeaders. "*" allows any header.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers readable by the browser, besides the CORS safelisted ones
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and the Authorization header. Any origin allowed is then echoed back instead of "*".
+	AllowCredentials bool
+	// MaxAge is how long browsers cache the preflight response. Zero leaves it to the browser.
+	MaxAge time.Duration
+	// Logger logs rejected cross-origin requests, if set
+	Logger log.Logger
+}
+
+// Handle sets the CORS headers of the response to r.
+// It returns true if r is a preflight request, which it has answered with 204 No Content.
+// Requests from disallowed origins get no CORS headers, so the browser rejects them.
+func (c *Config) Handle(w http.ResponseWriter, r *http.Request) bool {
+	h := w.Header()
+	o := r.Header.Get(origin)
+	preflight := r.Method == http.MethodOptions && r.Header.Get(requestMethod) != ""
+
+	// the response depends on these headers, caches must not serve it to other origins
+	h.Add(vary, origin)
+	if preflight {
+		h.Add(vary, requestMethod)
+		h.Add(vary, requestHeaders)
+	}
+
+	if o == "" {
+		return false
+	}
+
+	if preflight {
+		defer w.WriteHeader(http.StatusNoContent)
+	}
+
+	if !c.allowOrigin(o) {
+		c.reject(r, "origin not allowed")
+		return preflight
+	}
+
+	if preflight {
+		if !c.allowMethod(r.Header.Get(requestMethod)) {
+			c.reject(r, "method not allowed")
+			return true
+		}
+
+		headers := parseList(r.Header.Get(requestHeaders))
+		if !c.allowHeaders(headers) {
+			c.reject(r, "headers not allowed")
+			return true
+		}
+
+		h.Set(allowMethods, strings.Join(c.methods(), ", "))
+		if len(headers) > 0 {
+			// echo the requested headers, which may be allowed by "*"
+			h.Set(allowHeaders, strings.Join(headers, ", "))
+		}
+		if c.MaxAge > 0 {
+			h.Set(maxAge, strconv.Itoa(int(c.MaxAge.Seconds())))
+		}
+	} else if len(c.ExposedHeaders) > 0 {
+		h.Set(exposeHeaders, strings.Join(c.ExposedHeaders, ", "))
+	}
+
+	if c.anyOrigin() && !c.AllowCredentials {
+		h.Set(allowOrigin, "*")
+	} else {
+		h.Set(allowOrigin, o)
+	}
+
+	if c.AllowCredentials {
+		h.Set(allowCredentials, "true")
+	}
+
+	return preflight
+}
+
+func (c *Config) reject(r *http.Request, reason string) {
+	if c.Logger == nil {
+		return
+	}
+
+	log.InfoFields(c.Logger, "cors request rejected",
+		log.String("reason", reason),
+		log.String("origin", r.Header.Get(origin)),
+		log.String("method", r.Method),
+		log.String("endpoint", r.URL.Path),
+		log.String("request_method", r.Header.Get(requestMethod)),
+		log.String("request_headers", r.Header.Get(requestHeaders)),
+	)
+}
+
+func (c *Config) anyOrigin() bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) allowOrigin(o string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if matchOrigin(allowed, o) {
+			return true
+		}
+	}
+
+	for _, pattern := range c.AllowedOriginPatterns {
+		if pattern.MatchString(o) {
+			return true
+		}
+	}
+
+	return c.AllowOriginFunc != nil && c.AllowOriginFunc(o)
+}
+
+// matchOrigin matches o to allowed, which may contain a "*" wildcard
+func matchOrigin(allowed, o string) bool {
+	if allowed == "*" {
+		return true
+	}
+
+	i := strings.IndexByte(allowed, '*')
+	if i < 0 {
+		return strings.EqualFold(allowed, o)
+	}
+
+	prefix, suffix := strings.ToLower(allowed[:i]), strings.ToLower(allowed[i+1:])
+	o = strings.ToLower(o)
+
+	return len(o) > len(prefix)+len(suffix) && strings.HasPrefix(o, prefix) && strings.HasSuffix(o, suffix)
+}
+
+func (c *Config) methods() []string {
+	if len(c.AllowedMethods) == 0 {
+		return DefaultAllowedMethods
+	}
+
+	return c.AllowedMethods
+}
+
+func (c *Config) allowMethod(method string) bool {
+	for _, allowed := range c.methods() {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) allowHeaders(headers []string) bool {
+	allowed := c.AllowedHeaders
+	if len(allowed) == 0 {
+		allowed = DefaultAllowedHeaders
+	}
+
+	for _, header := range headers {
+		ok := false
+		for _, a := range allowed {
+			if a == "*" || strings.EqualFold(a, header) {
+				ok = true
+				break
+			}
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseList splits a comma separated header value
+func parseList(v string) []string {
+	var values []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			values = append(values, s)
+		}
+	}
+
+	return values
+}
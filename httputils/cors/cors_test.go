@@ -0,0 +1,233 @@
+package cors_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils/cors"
+	"github.com/viaduct-ai/vgo/testutils"
+)
+
+func TestConfigHandle(t *testing.T) {
+	t.Parallel()
+
+	cfg := &cors.Config{
+		AllowedOrigins:        []string{"https://app.viaduct.ai", "https://*.viaduct.dev"},
+		AllowedOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^http://localhost:\d+$`)},
+		AllowOriginFunc: func(origin string) bool {
+			return origin == "https://partner.example.com"
+		},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPut},
+		AllowedHeaders:   []string{"Content-Type"},
+		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+
+	preflightVary := []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}
+
+	tests := []struct {
+		name          string
+		method        string
+		headers       map[string]string
+		wantPreflight bool
+		wantHeaders   map[string]string
+		wantVary      []string
+	}{
+		{
+			name:     "Same Origin",
+			method:   http.MethodGet,
+			wantVary: []string{"Origin"},
+		},
+		{
+			name:    "Exact",
+			method:  http.MethodGet,
+			headers: map[string]string{"Origin": "https://app.viaduct.ai"},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.viaduct.ai",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-Id",
+			},
+			wantVary: []string{"Origin"},
+		},
+		{
+			name:        "Wildcard Subdomain",
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://pr-1.viaduct.dev"},
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "https://pr-1.viaduct.dev"},
+			wantVary:    []string{"Origin"},
+		},
+		{
+			name:     "Wildcard Without Subdomain",
+			method:   http.MethodGet,
+			headers:  map[string]string{"Origin": "https://.viaduct.dev"},
+			wantVary: []string{"Origin"},
+		},
+		{
+			name:        "Pattern",
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "http://localhost:3000"},
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "http://localhost:3000"},
+			wantVary:    []string{"Origin"},
+		},
+		{
+			name:        "Func",
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://partner.example.com"},
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "https://partner.example.com"},
+			wantVary:    []string{"Origin"},
+		},
+		{
+			name:        "Disallowed",
+			method:      http.MethodGet,
+			headers:     map[string]string{"Origin": "https://evil.com"},
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+			wantVary:    []string{"Origin"},
+		},
+		{
+			name:   "Preflight",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.viaduct.ai",
+				"Access-Control-Request-Method":  http.MethodPut,
+				"Access-Control-Request-Headers": "content-type",
+			},
+			wantPreflight: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.viaduct.ai",
+				"Access-Control-Allow-Methods":     "GET, PUT",
+				"Access-Control-Allow-Headers":     "content-type",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+				"Access-Control-Expose-Headers":    "",
+			},
+			wantVary: preflightVary,
+		},
+		{
+			name:   "Preflight Disallowed Method",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://app.viaduct.ai",
+				"Access-Control-Request-Method": http.MethodDelete,
+			},
+			wantPreflight: true,
+			wantHeaders:   map[string]string{"Access-Control-Allow-Origin": ""},
+			wantVary:      preflightVary,
+		},
+		{
+			name:   "Preflight Disallowed Header",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.viaduct.ai",
+				"Access-Control-Request-Method":  http.MethodGet,
+				"Access-Control-Request-Headers": "Content-Type, X-Secret",
+			},
+			wantPreflight: true,
+			wantHeaders:   map[string]string{"Access-Control-Allow-Origin": ""},
+			wantVary:      preflightVary,
+		},
+		{
+			name:    "Options",
+			method:  http.MethodOptions,
+			headers: map[string]string{"Origin": "https://app.viaduct.ai"},
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.viaduct.ai",
+				"Access-Control-Allow-Methods": "",
+			},
+			wantVary: []string{"Origin"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tt.method, "/v1/users", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rr := httptest.NewRecorder()
+			preflight := cfg.Handle(rr, req)
+
+			if preflight != tt.wantPreflight {
+				t.Errorf("want preflight %t. got %t", tt.wantPreflight, preflight)
+			}
+
+			if tt.wantPreflight && rr.Code != http.StatusNoContent {
+				t.Errorf("want status code %d. got %d", http.StatusNoContent, rr.Code)
+			}
+
+			for k, want := range tt.wantHeaders {
+				if got := rr.Header().Get(k); got != want {
+					t.Errorf("want %s %q. got %q", k, want, got)
+				}
+			}
+
+			if got := rr.Header()["Vary"]; !reflect.DeepEqual(got, tt.wantVary) {
+				t.Errorf("want Vary %v. got %v", tt.wantVary, got)
+			}
+		})
+	}
+}
+
+func TestConfigHandleAnyOrigin(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		allowCredentials bool
+		wantOrigin       string
+	}{
+		{
+			name:       "Without Credentials",
+			wantOrigin: "*",
+		},
+		{
+			name:             "With Credentials",
+			allowCredentials: true,
+			wantOrigin:       "https://anywhere.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &cors.Config{AllowedOrigins: []string{"*"}, AllowCredentials: tt.allowCredentials}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Origin", "https://anywhere.com")
+
+			rr := httptest.NewRecorder()
+			cfg.Handle(rr, req)
+
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("want origin %s. got %s", tt.wantOrigin, got)
+			}
+		})
+	}
+}
+
+func TestConfigHandleLogging(t *testing.T) {
+	t.Parallel()
+
+	logger := testutils.NewTestLogger()
+	cfg := &cors.Config{Logger: logger}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	cfg.Handle(httptest.NewRecorder(), req)
+
+	if len(logger.InfoLogs) != 1 || !strings.Contains(logger.InfoLogs[0].(string), "cors") {
+		t.Fatalf("want rejected request to be logged. got %v", logger.InfoLogs)
+	}
+
+	if got := logger.Context["origin"]; got != "https://evil.com" {
+		t.Errorf("want origin https://evil.com. got %v", got)
+	}
+}
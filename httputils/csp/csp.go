@@ -0,0 +1,159 @@
+// Package csp builds Content-Security-Policy header values.
+// https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy
+package csp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+// Directive is a CSP directive name
+type Directive string
+
+// Directives
+const (
+	DefaultSrc              Directive = "default-src"
+	ScriptSrc               Directive = "script-src"
+	StyleSrc                Directive = "style-src"
+	ImgSrc                  Directive = "img-src"
+	ConnectSrc              Directive = "connect-src"
+	FontSrc                 Directive = "font-src"
+	ObjectSrc               Directive = "object-src"
+	MediaSrc                Directive = "media-src"
+	FrameSrc                Directive = "frame-src"
+	WorkerSrc               Directive = "worker-src"
+	ManifestSrc             Directive = "manifest-src"
+	FrameAncestors          Directive = "frame-ancestors"
+	BaseURI                 Directive = "base-uri"
+	FormAction              Directive = "form-action"
+	ReportURI               Directive = "report-uri"
+	ReportTo                Directive = "report-to"
+	UpgradeInsecureRequests Directive = "upgrade-insecure-requests"
+)
+
+// Source is a CSP source expression, such as a keyword, scheme or host
+type Source string
+
+// Sources
+const (
+	Self          Source = "'self'"
+	None          Source = "'none'"
+	UnsafeInline  Source = "'unsafe-inline'"
+	UnsafeEval    Source = "'unsafe-eval'"
+	StrictDynamic Source = "'strict-dynamic'"
+	Data          Source = "data:"
+	Blob          Source = "blob:"
+	HTTPS         Source = "https:"
+)
+
+type directive struct {
+	name    Directive
+	sources []Source
+	nonce   bool
+}
+
+// Policy is a Content-Security-Policy.
+// Its methods return the Policy so calls can be chained:
+//
+//	csp.New().
+//		Add(csp.DefaultSrc, csp.Self).
+//		Add(csp.ScriptSrc, csp.Self, csp.StrictDynamic).
+//		Nonce(csp.ScriptSrc).
+//		Add(csp.FrameAncestors, csp.None)
+type Policy struct {
+	directives []*directive
+}
+
+// New returns a reference to a new, empty Policy
+func New() *Policy {
+	return &Policy{}
+}
+
+// Add adds sources to directive d, in the order directives are first added
+func (p *Policy) Add(d Directive, sources ...Source) *Policy {
+	dir := p.directive(d)
+	dir.sources = append(dir.sources, sources...)
+	return p
+}
+
+// Nonce adds the per request nonce to the sources of directives ds, such as ScriptSrc and StyleSrc
+func (p *Policy) Nonce(ds ...Directive) *Policy {
+	for _, d := range ds {
+		p.directive(d).nonce = true
+	}
+	return p
+}
+
+// UsesNonce returns true if any directive includes the per request nonce
+func (p *Policy) UsesNonce() bool {
+	for _, dir := range p.directives {
+		if dir.nonce {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the header value of the Policy, with nonce added to the directives set with Nonce
+func (p *Policy) String(nonce string) string {
+	var b strings.Builder
+
+	for i, dir := range p.directives {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+
+		b.WriteString(string(dir.name))
+
+		for _, s := range dir.sources {
+			b.WriteByte(' ')
+			b.WriteString(string(s))
+		}
+
+		if dir.nonce && nonce != "" {
+			b.WriteString(" 'nonce-")
+			b.WriteString(nonce)
+			b.WriteByte('\'')
+		}
+	}
+
+	return b.String()
+}
+
+func (p *Policy) directive(d Directive) *directive {
+	for _, dir := range p.directives {
+		if dir.name == d {
+			return dir
+		}
+	}
+
+	dir := &directive{name: d}
+	p.directives = append(p.directives, dir)
+	return dir
+}
+
+// NewNonce returns a random, base64 encoded nonce
+func NewNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+type nonceKey struct{}
+
+// ContextWithNonce returns a copy of ctx holding nonce
+func ContextWithNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, nonceKey{}, nonce)
+}
+
+// NonceFromContext returns the nonce of the request, to be set on inline scripts and styles,
+// or an empty string if there is none
+func NonceFromContext(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceKey{}).(string)
+	return nonce
+}
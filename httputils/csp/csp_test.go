@@ -0,0 +1,72 @@
+package csp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils/csp"
+)
+
+func TestPolicyString(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		policy *csp.Policy
+		nonce  string
+		want   string
+	}{
+		{
+			name:   "Empty",
+			policy: csp.New(),
+		},
+		{
+			name: "Directives",
+			policy: csp.New().
+				Add(csp.DefaultSrc, csp.Self).
+				Add(csp.ImgSrc, csp.Self, csp.Data).
+				Add(csp.DefaultSrc, "https://cdn.viaduct.ai").
+				Add(csp.UpgradeInsecureRequests),
+			want: "default-src 'self' https://cdn.viaduct.ai; img-src 'self' data:; upgrade-insecure-requests",
+		},
+		{
+			name: "Nonce",
+			policy: csp.New().
+				Add(csp.ScriptSrc, csp.StrictDynamic).
+				Nonce(csp.ScriptSrc, csp.StyleSrc),
+			nonce: "abc",
+			want:  "script-src 'strict-dynamic' 'nonce-abc'; style-src 'nonce-abc'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.String(tt.nonce); got != tt.want {
+				t.Errorf("want %q. got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNonce(t *testing.T) {
+	t.Parallel()
+
+	a, err := csp.NewNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := csp.NewNonce()
+	if len(a) != 24 || a == b {
+		t.Errorf("want unique 24 character nonces. got %s and %s", a, b)
+	}
+
+	ctx := csp.ContextWithNonce(context.Background(), a)
+	if got := csp.NonceFromContext(ctx); got != a {
+		t.Errorf("want nonce %s. got %s", a, got)
+	}
+
+	if got := csp.NonceFromContext(context.Background()); got != "" {
+		t.Errorf("want no nonce. got %s", got)
+	}
+}
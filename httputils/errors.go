@@ -0,0 +1,142 @@
+package httputils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// StatusClientClosedRequest is the non-standard status of requests cancelled by the client,
+// as used by nginx
+const StatusClientClosedRequest = 499
+
+// bodyTooLarge is the error of http.MaxBytesReader, which has no exported error before Go 1.19
+const bodyTooLarge = "http: request body too large"
+
+// codeRequestTooLarge and msgRequestTooLarge are the code and message of every 413 error,
+// classified or returned by the request decoders
+const (
+	codeRequestTooLarge = "request_too_large"
+	msgRequestTooLarge  = "request body is too large"
+)
+
+// classifiedError is the APIError ServeError serves for well-known errors that are not APIErrors
+type classifiedError struct {
+	status int
+	code   string
+	msg    string
+	err    error
+}
+
+func (ce *classifiedError) Error() string {
+	return ce.err.Error()
+}
+
+func (ce *classifiedError) Status() int {
+	return ce.status
+}
+
+func (ce *classifiedError) Message() string {
+	return ce.msg
+}
+
+func (ce *classifiedError) Code() string {
+	return ce.code
+}
+
+func (ce *classifiedError) Unwrap() error {
+	return ce.err
+}
+
+// ErrorMapper converts errors that are not APIErrors to APIErrors, such as sql.ErrNoRows to a 404.
+// It returns nil for errors it does not apply to.
+type ErrorMapper func(err error) APIError
+
+var (
+	mappersMu sync.RWMutex
+	mappers   []ErrorMapper
+)
+
+// RegisterErrorMapper registers a mapper of the errors served by ServeError that are not APIErrors.
+// Mappers are tried in the order they are registered, before the built-in classification of
+// context, body limit and network errors.
+func RegisterErrorMapper(m ErrorMapper) {
+	mappersMu.Lock()
+	defer mappersMu.Unlock()
+
+	mappers = append(mappers, m)
+}
+
+// mapError converts err to an APIError with the registered mappers, then the built-in classification.
+// It returns nil if err is unknown.
+func mapError(err error) APIError {
+	if err == nil {
+		return nil
+	}
+
+	// mappers are called without the lock, so they can register mappers or take their own locks.
+	// mappers is only appended to, so the elements of the copy are never modified.
+	mappersMu.RLock()
+	registered := mappers
+	mappersMu.RUnlock()
+
+	for _, m := range registered {
+		if apiError := m(err); apiError != nil {
+			return apiError
+		}
+	}
+
+	return classifyError(err)
+}
+
+// classifyError converts context, body limit and network errors to APIErrors
+func classifyError(err error) APIError {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return &classifiedError{
+			status: StatusClientClosedRequest,
+			code:   "client_closed_request",
+			msg:    "the request was cancelled by the client",
+			err:    err,
+		}
+	// before net.Error, as context.DeadlineExceeded is a net.Error timeout too
+	case errors.Is(err, context.DeadlineExceeded):
+		return &classifiedError{
+			status: http.StatusGatewayTimeout,
+			code:   "timeout",
+			msg:    "the request timed out",
+			err:    err,
+		}
+	case isBodyTooLarge(err):
+		return &classifiedError{
+			status: http.StatusRequestEntityTooLarge,
+			code:   codeRequestTooLarge,
+			msg:    msgRequestTooLarge,
+			err:    err,
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &classifiedError{
+			status: http.StatusServiceUnavailable,
+			code:   "unavailable",
+			msg:    "the service is temporarily unavailable",
+			err:    err,
+		}
+	}
+
+	return nil
+}
+
+// isBodyTooLarge reports whether err is or wraps the error of http.MaxBytesReader
+func isBodyTooLarge(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if err.Error() == bodyTooLarge {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package httputils_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/apierrors"
+	"github.com/viaduct-ai/vgo/testutils"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// bodyTooLargeError returns the error of reading a body over the limit of http.MaxBytesReader
+func bodyTooLargeError() error {
+	body := http.MaxBytesReader(httptest.NewRecorder(), ioutil.NopCloser(strings.NewReader("too large")), 1)
+	_, err := ioutil.ReadAll(body)
+	return err
+}
+
+func TestServeErrorClassification(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "canceled",
+			err:        fmt.Errorf("query users: %w", context.Canceled),
+			wantStatus: httputils.StatusClientClosedRequest,
+			wantCode:   "client_closed_request",
+		},
+		{
+			name:       "deadline exceeded",
+			err:        fmt.Errorf("query users: %w", context.DeadlineExceeded),
+			wantStatus: http.StatusGatewayTimeout,
+			wantCode:   "timeout",
+		},
+		{
+			name:       "body too large",
+			err:        fmt.Errorf("read body: %w", bodyTooLargeError()),
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   "request_too_large",
+		},
+		{
+			name:       "item error wrapping a context error",
+			err:        &httputils.ItemError{Index: 2, Err: context.Canceled},
+			wantStatus: httputils.StatusClientClosedRequest,
+			wantCode:   "client_closed_request",
+		},
+		{
+			name:       "net timeout",
+			err:        fmt.Errorf("call billing: %w", timeoutError{}),
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "unavailable",
+		},
+		{
+			name:       "API error wrapping a context error",
+			err:        apierrors.Conflict("").WithCause(context.Canceled),
+			wantStatus: http.StatusConflict,
+			wantCode:   "conflict",
+		},
+		{
+			name:       "unknown",
+			err:        errors.New("unknown"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "internal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			httputils.ServeError(rr, tt.err)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("want status code %d. got %d", tt.wantStatus, rr.Code)
+			}
+
+			resp := decodeErrorResponse(t, rr)
+			if resp.Code != tt.wantCode {
+				t.Errorf("want code %s. got %s", tt.wantCode, resp.Code)
+			}
+		})
+	}
+}
+
+func TestRegisterErrorMapper(t *testing.T) {
+	httputils.RegisterErrorMapper(func(err error) httputils.APIError {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apierrors.NotFound("").WithCause(err)
+		}
+		return nil
+	})
+
+	rr := httptest.NewRecorder()
+	httputils.ServeError(rr, fmt.Errorf("get user: %w", sql.ErrNoRows))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("want status code %d. got %d", http.StatusNotFound, rr.Code)
+	}
+
+	if resp := decodeErrorResponse(t, rr); resp.Code != "not_found" {
+		t.Errorf("want code not_found. got %s", resp.Code)
+	}
+
+	// other errors are still classified
+	rr = httptest.NewRecorder()
+	httputils.ServeError(rr, context.DeadlineExceeded)
+
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("want status code %d. got %d", http.StatusGatewayTimeout, rr.Code)
+	}
+}
+
+func TestServeErrorLoggingCanceled(t *testing.T) {
+	l := testutils.NewTestLogger()
+	httputils.SetErrorLogger(l)
+	defer httputils.SetErrorLogger(nil)
+
+	httputils.ServeError(httptest.NewRecorder(), context.Canceled)
+
+	if len(l.DebugLogs) != 1 || len(l.ErrorLogs) != 0 {
+		t.Errorf("want 1 debug log. got %v debug and %v error logs", l.DebugLogs, l.ErrorLogs)
+	}
+
+	httputils.ServeError(httptest.NewRecorder(), context.DeadlineExceeded)
+
+	if len(l.ErrorLogs) != 1 {
+		t.Errorf("want 1 error log. got %v", l.ErrorLogs)
+	}
+}
+
+func decodeErrorResponse(t *testing.T, rr *httptest.ResponseRecorder) httputils.APIErrorResponse {
+	t.Helper()
+
+	var resp httputils.APIErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestRegisterErrorMapperFromMapper(t *testing.T) {
+	errLazy := errors.New("lazy")
+
+	var once sync.Once
+	httputils.RegisterErrorMapper(func(err error) httputils.APIError {
+		if !errors.Is(err, errLazy) {
+			return nil
+		}
+
+		// mappers are called without the registry lock, so they can register mappers
+		once.Do(func() {
+			httputils.RegisterErrorMapper(func(error) httputils.APIError { return nil })
+		})
+		return apierrors.Conflict("")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		httputils.ServeError(httptest.NewRecorder(), errLazy)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("want ServeError to return. got a deadlock")
+	}
+}
+
+func TestRequestTooLargeCode(t *testing.T) {
+	t.Parallel()
+
+	body := `{"name": "` + strings.Repeat("a", 1<<20) + `"}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		decode      func(w http.ResponseWriter, r *http.Request, dst interface{}) error
+	}{
+		{name: "DecodeJSONBody", contentType: httputils.ContentTypeJSON, decode: httputils.DecodeJSONBody},
+		{name: "DecodeBody", contentType: httputils.ContentTypeJSON, decode: httputils.DecodeBody},
+		{name: "DecodePatch", contentType: httputils.ContentTypeMergePatch, decode: httputils.DecodePatch},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			r.Header.Set(httputils.ContentType, tt.contentType)
+
+			var dst struct{ Name string }
+			err := tt.decode(httptest.NewRecorder(), r, &dst)
+
+			rr := httptest.NewRecorder()
+			httputils.ServeError(rr, err)
+
+			if rr.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("want status code %d. got %d", http.StatusRequestEntityTooLarge, rr.Code)
+			}
+
+			// the same code and message as classified body limit errors
+			resp := decodeErrorResponse(t, rr)
+			if resp.Code != "request_too_large" {
+				t.Errorf("want code request_too_large. got %s", resp.Code)
+			}
+
+			if resp.Message != "request body is too large" {
+				t.Errorf("want message %q. got %q", "request body is too large", resp.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package httputils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ETag is a constant for the ETag header
+	ETag = http.CanonicalHeaderKey("ETag")
+	// LastModified is a constant for the Last-Modified header
+	LastModified = http.CanonicalHeaderKey("Last-Modified")
+
+	ifMatch           = http.CanonicalHeaderKey("If-Match")
+	ifNoneMatch       = http.CanonicalHeaderKey("If-None-Match")
+	ifUnmodifiedSince = http.CanonicalHeaderKey("If-Unmodified-Since")
+)
+
+type preconditionFailed struct {
+	msg string
+}
+
+func (pf *preconditionFailed) Error() string {
+	return pf.msg
+}
+
+func (pf *preconditionFailed) Status() int {
+	return http.StatusPreconditionFailed
+}
+
+func (pf *preconditionFailed) Message() string {
+	return pf.msg
+}
+
+func (pf *preconditionFailed) Code() string {
+	return "precondition_failed"
+}
+
+// ErrPreconditionFailed is the APIError returned by CheckPreconditions when the resource was modified
+var ErrPreconditionFailed APIError = &preconditionFailed{msg: "resource has been modified since it was read"}
+
+// StrongETag returns a strong ETag of body, for responses that are byte for byte identical
+func StrongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// WeakETag returns a weak ETag of body, for responses that are semantically equivalent,
+// such as when the encoding of the body may change
+func WeakETag(body []byte) string {
+	return "W/" + StrongETag(body)
+}
+
+// ServeJSONWithETag serves a JSON response like ServeJSON, with an ETag computed by etag from the body.
+// The body is pretty-printed if r has a "pretty" query parameter.
+// A nil etag defaults to StrongETag. If r is a GET or HEAD request with an If-None-Match header
+// matching the ETag of a 2xx response, 304 Not Modified is served without a body instead.
+func ServeJSONWithETag(w http.ResponseWriter, r *http.Request, status int, body interface{}, etag func([]byte) string) {
+	pretty := prettyRequest(r)
+
+	buf, err := encodeJSON(body, pretty)
+	if err != nil {
+		serveJSON(w, http.StatusInternalServerError, internalError, pretty)
+		return
+	}
+	defer putBuffer(buf)
+
+	if etag == nil {
+		etag = StrongETag
+	}
+
+	tag := etag(buf.Bytes())
+	w.Header().Set(ETag, tag)
+
+	if status >= 200 && status < 300 && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		if inm := r.Header.Get(ifNoneMatch); inm != "" && matchETag(inm, tag, false) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Header().Set(ContentType, ContentTypeJSON)
+	w.Header().Set(contentLength, strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// Version identifies the current state of a resource for conditional requests.
+// Either or both fields may be set.
+type Version struct {
+	ETag         string
+	LastModified time.Time
+}
+
+// SetVersion sets the ETag and Last-Modified headers of v, so clients can make conditional requests
+func SetVersion(w http.ResponseWriter, v Version) {
+	if v.ETag != "" {
+		w.Header().Set(ETag, v.ETag)
+	}
+
+	if !v.LastModified.IsZero() {
+		w.Header().Set(LastModified, v.LastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// CheckPreconditions evaluates the If-Match and If-Unmodified-Since headers of a write request
+// against v, the current version of the resource, for optimistic concurrency control.
+// It returns ErrPreconditionFailed, a 412 APIError, if the client's version is outdated.
+// If-Unmodified-Since is ignored when If-Match is present, as per RFC 7232.
+func CheckPreconditions(r *http.Request, v Version) error {
+	if im := r.Header.Get(ifMatch); im != "" {
+		exists := v.ETag != "" || !v.LastModified.IsZero()
+
+		if strings.TrimSpace(im) == "*" {
+			if !exists {
+				return ErrPreconditionFailed
+			}
+			return nil
+		}
+
+		if v.ETag == "" || !matchETag(im, v.ETag, true) {
+			return ErrPreconditionFailed
+		}
+		return nil
+	}
+
+	if ius := r.Header.Get(ifUnmodifiedSince); ius != "" && !v.LastModified.IsZero() {
+		t, err := http.ParseTime(ius)
+		if err != nil {
+			// an invalid date is ignored, as per RFC 7232
+			return nil
+		}
+
+		// HTTP dates have a resolution of seconds
+		if v.LastModified.Truncate(time.Second).After(t) {
+			return ErrPreconditionFailed
+		}
+	}
+
+	return nil
+}
+
+// matchETag reports whether any of the ETags of the comma separated header value matches tag.
+// Strong comparison never matches weak ETags, weak comparison ignores the W/ prefix.
+func matchETag(header, tag string, strong bool) bool {
+	if strong && strings.HasPrefix(tag, "W/") {
+		return false
+	}
+
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" {
+			return true
+		}
+
+		if strong {
+			if t == tag {
+				return true
+			}
+			continue
+		}
+
+		if strings.TrimPrefix(t, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
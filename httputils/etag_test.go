@@ -0,0 +1,216 @@
+package httputils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils"
+)
+
+func TestServeJSONWithETag(t *testing.T) {
+	t.Parallel()
+
+	body := map[string]string{"test": "test"}
+
+	rr := httptest.NewRecorder()
+	httputils.ServeJSONWithETag(rr, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, body, nil)
+
+	strong := rr.Header().Get("ETag")
+	if !strings.HasPrefix(strong, `"`) || strong != httputils.StrongETag(rr.Body.Bytes()) {
+		t.Fatalf("want strong ETag of the body. got %s", strong)
+	}
+
+	weak := httputils.WeakETag(rr.Body.Bytes())
+
+	tests := []struct {
+		name        string
+		method      string
+		status      int
+		ifNoneMatch string
+		etag        func([]byte) string
+		wantStatus  int
+		wantETag    string
+	}{
+		{
+			name:       "No Condition",
+			method:     http.MethodGet,
+			status:     http.StatusOK,
+			wantStatus: http.StatusOK,
+			wantETag:   strong,
+		},
+		{
+			name:        "Match",
+			method:      http.MethodGet,
+			status:      http.StatusOK,
+			ifNoneMatch: `"other", ` + strong,
+			wantStatus:  http.StatusNotModified,
+			wantETag:    strong,
+		},
+		{
+			name:        "Weak Match",
+			method:      http.MethodGet,
+			status:      http.StatusOK,
+			ifNoneMatch: strong,
+			etag:        httputils.WeakETag,
+			wantStatus:  http.StatusNotModified,
+			wantETag:    weak,
+		},
+		{
+			name:        "No Match",
+			method:      http.MethodGet,
+			status:      http.StatusOK,
+			ifNoneMatch: `"other"`,
+			wantStatus:  http.StatusOK,
+			wantETag:    strong,
+		},
+		{
+			name:        "POST",
+			method:      http.MethodPost,
+			status:      http.StatusCreated,
+			ifNoneMatch: strong,
+			wantStatus:  http.StatusCreated,
+			wantETag:    strong,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+
+			rr := httptest.NewRecorder()
+			httputils.ServeJSONWithETag(rr, req, tt.status, body, tt.etag)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("want status code %d. got %d", tt.wantStatus, rr.Code)
+			}
+
+			if got := rr.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("want ETag %s. got %s", tt.wantETag, got)
+			}
+
+			if tt.wantStatus == http.StatusNotModified && rr.Body.Len() > 0 {
+				t.Errorf("want no body. got %s", rr.Body)
+			}
+		})
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	t.Parallel()
+
+	modified := time.Date(2021, 6, 1, 12, 0, 0, 500, time.UTC)
+	version := httputils.Version{ETag: `"v2"`, LastModified: modified}
+
+	tests := []struct {
+		name    string
+		version httputils.Version
+		headers map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "No Condition",
+			version: version,
+		},
+		{
+			name:    "If-Match",
+			version: version,
+			headers: map[string]string{"If-Match": `"v1", "v2"`},
+		},
+		{
+			name:    "If-Match Outdated",
+			version: version,
+			headers: map[string]string{"If-Match": `"v1"`},
+			wantErr: true,
+		},
+		{
+			name:    "If-Match Weak",
+			version: version,
+			headers: map[string]string{"If-Match": `W/"v2"`},
+			wantErr: true,
+		},
+		{
+			name:    "If-Match Any",
+			version: version,
+			headers: map[string]string{"If-Match": "*"},
+		},
+		{
+			name:    "If-Match Any Missing",
+			headers: map[string]string{"If-Match": "*"},
+			wantErr: true,
+		},
+		{
+			name:    "If-Unmodified-Since",
+			version: version,
+			headers: map[string]string{"If-Unmodified-Since": modified.Format(http.TimeFormat)},
+		},
+		{
+			name:    "If-Unmodified-Since Outdated",
+			version: version,
+			headers: map[string]string{"If-Unmodified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)},
+			wantErr: true,
+		},
+		{
+			name:    "If-Unmodified-Since Invalid",
+			version: version,
+			headers: map[string]string{"If-Unmodified-Since": "yesterday"},
+		},
+		{
+			name:    "If-Match Precedence",
+			version: version,
+			headers: map[string]string{
+				"If-Match":            `"v2"`,
+				"If-Unmodified-Since": modified.Add(-time.Hour).Format(http.TimeFormat),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			err := httputils.CheckPreconditions(req, tt.version)
+
+			if tt.wantErr && err != httputils.ErrPreconditionFailed {
+				t.Errorf("want %v. got %v", httputils.ErrPreconditionFailed, err)
+			}
+
+			if !tt.wantErr && err != nil {
+				t.Errorf("want no error. got %v", err)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	httputils.ServeError(rr, httputils.ErrPreconditionFailed)
+
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("want status code %d. got %d", http.StatusPreconditionFailed, rr.Code)
+	}
+}
+
+func TestSetVersion(t *testing.T) {
+	t.Parallel()
+
+	rr := httptest.NewRecorder()
+	httputils.SetVersion(rr, httputils.Version{
+		ETag:         `"v2"`,
+		LastModified: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+	})
+
+	if got := rr.Header().Get("ETag"); got != `"v2"` {
+		t.Errorf("want ETag \"v2\". got %s", got)
+	}
+
+	if got, want := rr.Header().Get("Last-Modified"), "Tue, 01 Jun 2021 12:00:00 GMT"; got != want {
+		t.Errorf("want Last-Modified %s. got %s", want, got)
+	}
+}
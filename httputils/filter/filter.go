@@ -0,0 +1,267 @@
+// Package filter parses the filter and sort query parameters of list endpoints and translates them
+// into parameterized SQL.
+//
+// A filter is a boolean expression of comparisons of fields with values:
+//
+//	filter     = or
+//	or         = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" or ")" | comparison
+//	comparison = field op value | field "in" "(" value { "," value } ")"
+//	op         = "eq" | "ne" | "gt" | "ge" | "lt" | "le" | "contains" | "startswith"
+//	value      = 'quoted string' | number | "true" | "false" | date | datetime
+//
+// such as status eq 'active' and created_at gt 2024-01-01. Keywords are case-insensitive, quotes are
+// escaped by doubling them, dates are YYYY-MM-DD and datetimes RFC 3339.
+//
+// A sort is a comma-separated list of fields, each prefixed with "-" to sort in descending order,
+// such as -created_at,id.
+//
+// Only the fields of a Schema can be filtered and sorted on, and values must match their Type, so
+// the SQL generated only ever contains the columns of the Schema and placeholders for values.
+package filter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Type is the type of the values of a Field
+type Type int
+
+// Types of fields
+const (
+	String Type = iota
+	Int
+	Float
+	Bool
+	Time
+)
+
+func (t Type) String() string {
+	switch t {
+	case Int:
+		return "integer"
+	case Float:
+		return "number"
+	case Bool:
+		return "boolean"
+	case Time:
+		return "date"
+	default:
+		return "string"
+	}
+}
+
+// Op is a comparison operator
+type Op string
+
+// Comparison operators
+const (
+	Eq         Op = "eq"
+	Ne         Op = "ne"
+	Gt         Op = "gt"
+	Ge         Op = "ge"
+	Lt         Op = "lt"
+	Le         Op = "le"
+	In         Op = "in"
+	Contains   Op = "contains"
+	StartsWith Op = "startswith"
+)
+
+// ops are the operators applying to each Type
+var ops = map[Type][]Op{
+	String: {Eq, Ne, Gt, Ge, Lt, Le, In, Contains, StartsWith},
+	Int:    {Eq, Ne, Gt, Ge, Lt, Le, In},
+	Float:  {Eq, Ne, Gt, Ge, Lt, Le, In},
+	Bool:   {Eq, Ne},
+	Time:   {Eq, Ne, Gt, Ge, Lt, Le, In},
+}
+
+func hasOp(list []Op, op Op) bool {
+	for _, o := range list {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Field is a field that can be filtered on
+type Field struct {
+	Type Type
+	// Column is the SQL expression of the field. Defaults to the name of the field.
+	Column string
+	// Ops restricts the operators allowed on the field. Defaults to all the operators of its Type.
+	Ops []Op
+	// Sortable allows sorting on the field
+	Sortable bool
+}
+
+func (f *Field) allows(op Op) bool {
+	if f.Ops != nil && !hasOp(f.Ops, op) {
+		return false
+	}
+	return hasOp(ops[f.Type], op)
+}
+
+// Schema is the allowlist of the fields of an endpoint, by name
+type Schema map[string]Field
+
+func (s Schema) column(name string) string {
+	if f := s[name]; f.Column != "" {
+		return f.Column
+	}
+	return name
+}
+
+// Error is the APIError returned for invalid filter and sort parameters.
+// It points at the offending token of the parameter.
+type Error struct {
+	// Param is the query parameter, "filter" or "sort"
+	Param string
+	// Pos is the 1-based position of the offending token in the parameter
+	Pos int
+	// Token is the offending token, empty at the end of the parameter
+	Token  string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%s: %s at end of input", e.Param, e.Reason)
+	}
+	return fmt.Sprintf("%s: %s at position %d", e.Param, e.Reason, e.Pos)
+}
+
+// Status is 400 Bad Request
+func (e *Error) Status() int {
+	return http.StatusBadRequest
+}
+
+// Message describes the error and where it is
+func (e *Error) Message() string {
+	return e.Error()
+}
+
+// Code is "invalid_filter" or "invalid_sort"
+func (e *Error) Code() string {
+	return "invalid_" + e.Param
+}
+
+// Query is the parsed filter and sort of a request
+type Query struct {
+	// Filter is nil if the request has no filter
+	Filter Expr
+	Sort   []SortKey
+
+	schema Schema
+}
+
+// Parse parses the filter and sort query parameters of r against schema.
+// Invalid parameters are a 400 *Error.
+func Parse(r *http.Request, schema Schema) (*Query, error) {
+	q := r.URL.Query()
+
+	filter, err := ParseFilter(q.Get("filter"), schema)
+	if err != nil {
+		return nil, err
+	}
+
+	sort, err := ParseSort(q.Get("sort"), schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Query{Filter: filter, Sort: sort, schema: schema}, nil
+}
+
+// Where returns the SQL condition of the filter, without the WHERE keyword, and args with its values appended.
+// Pass the arguments of the statement before the condition as args, so placeholders are numbered after them.
+// It returns an empty condition if there is no filter.
+func (q *Query) Where(ph Placeholder, args []interface{}) (string, []interface{}) {
+	if q.Filter == nil {
+		return "", args
+	}
+	return Where(q.Filter, q.schema, ph, args)
+}
+
+// OrderBy returns the SQL ordering of the sort, without the ORDER BY keywords, or an empty string
+func (q *Query) OrderBy() string {
+	return OrderBy(q.Sort, q.schema)
+}
+
+// SortKey is a field to sort on
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a comma-separated list of fields, each prefixed with "-" for descending order.
+// The fields must be Sortable in schema. An empty s returns no keys.
+func ParseSort(s string, schema Schema) ([]SortKey, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var keys []SortKey
+	seen := map[string]bool{}
+
+	next := 1
+	for _, part := range strings.Split(s, ",") {
+		pos := next + len(part) - len(strings.TrimLeft(part, " "))
+		next += len(part) + 1
+
+		token := strings.TrimSpace(part)
+		key := SortKey{Field: token}
+
+		switch {
+		case strings.HasPrefix(token, "-"):
+			key = SortKey{Field: token[1:], Desc: true}
+		case strings.HasPrefix(token, "+"):
+			key.Field = token[1:]
+		}
+
+		if key.Field == "" {
+			err := &Error{Param: "sort", Pos: pos, Token: token, Reason: "expected field"}
+			if err.Token == "" && pos <= len(s) {
+				// point at the comma following the missing field
+				err.Token = ","
+			}
+			return nil, err
+		}
+
+		f, ok := schema[key.Field]
+		if !ok {
+			return nil, &Error{Param: "sort", Pos: pos, Token: token, Reason: fmt.Sprintf("unknown field %q", key.Field)}
+		}
+
+		if !f.Sortable {
+			return nil, &Error{Param: "sort", Pos: pos, Token: token, Reason: fmt.Sprintf("field %q is not sortable", key.Field)}
+		}
+
+		if seen[key.Field] {
+			return nil, &Error{Param: "sort", Pos: pos, Token: token, Reason: fmt.Sprintf("duplicate field %q", key.Field)}
+		}
+		seen[key.Field] = true
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// OrderBy returns the SQL ordering of keys, without the ORDER BY keywords, or an empty string
+func OrderBy(keys []SortKey, schema Schema) string {
+	terms := make([]string, len(keys))
+	for i, k := range keys {
+		dir := "ASC"
+		if k.Desc {
+			dir = "DESC"
+		}
+		terms[i] = schema.column(k.Field) + " " + dir
+	}
+
+	return strings.Join(terms, ", ")
+}
@@ -0,0 +1,122 @@
+package filter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils/filter"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		sort    string
+		want    []filter.SortKey
+		wantMsg string
+	}{
+		{
+			name: "empty",
+			sort: "",
+		},
+		{
+			name: "keys",
+			sort: "-created_at, +status,id",
+			want: []filter.SortKey{{Field: "created_at", Desc: true}, {Field: "status"}, {Field: "id"}},
+		},
+		{
+			name:    "unknown field",
+			sort:    "id,-password",
+			wantMsg: `sort: unknown field "password" at position 4`,
+		},
+		{
+			name:    "not sortable",
+			sort:    "age",
+			wantMsg: `sort: field "age" is not sortable at position 1`,
+		},
+		{
+			name:    "duplicate",
+			sort:    "id, -id",
+			wantMsg: `sort: duplicate field "id" at position 5`,
+		},
+		{
+			name:    "empty key",
+			sort:    "id,,status",
+			wantMsg: `sort: expected field at position 4`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filter.ParseSort(tt.sort, schema)
+
+			if tt.wantMsg != "" {
+				ferr, ok := err.(*filter.Error)
+				if !ok {
+					t.Fatalf("want *filter.Error. got %v", err)
+				}
+				if ferr.Message() != tt.wantMsg {
+					t.Errorf("want message %s. got %s", tt.wantMsg, ferr.Message())
+				}
+				if ferr.Code() != "invalid_sort" {
+					t.Errorf("want code invalid_sort. got %s", ferr.Code())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %v. got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	q := url.Values{}
+	q.Set("filter", "status eq 'active' and created_at gt 2024-01-01")
+	q.Set("sort", "-created_at,id")
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/users?"+q.Encode(), nil)
+
+	query, err := filter.Parse(r, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	where, args := query.Where(filter.Dollar, nil)
+	if want := "(status = $1 AND created_at > $2)"; where != want {
+		t.Errorf("want %s. got %s", want, where)
+	}
+
+	if len(args) != 2 {
+		t.Errorf("want 2 args. got %d", len(args))
+	}
+
+	if want, got := "created_at DESC, id ASC", query.OrderBy(); got != want {
+		t.Errorf("want %s. got %s", want, got)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
+
+	query, err := filter.Parse(r, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	where, args := query.Where(filter.Question, nil)
+	if where != "" || args != nil {
+		t.Errorf("want no condition. got %q %v", where, args)
+	}
+
+	if got := query.OrderBy(); got != "" {
+		t.Errorf("want no ordering. got %s", got)
+	}
+}
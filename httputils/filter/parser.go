@@ -0,0 +1,373 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// maxFilterLength bounds the length of filters
+	maxFilterLength = 2048
+	// maxDepth bounds the nesting of parentheses and "not"
+	maxDepth = 32
+)
+
+// Expr is a node of a filter expression: a *Logical, *Not or *Comparison
+type Expr interface {
+	expr()
+}
+
+// Logical is the "and" or "or" of two expressions
+type Logical struct {
+	// Op is "and" or "or"
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// Not negates an expression
+type Not struct {
+	Expr Expr
+}
+
+// Comparison compares a field with values. Values are string, int64, float64, bool or time.Time
+// according to the Type of the field, and there are several of them only for In.
+type Comparison struct {
+	Field  string
+	Op     Op
+	Values []interface{}
+}
+
+func (*Logical) expr()    {}
+func (*Not) expr()        {}
+func (*Comparison) expr() {}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	// text is the unquoted value of strings
+	text string
+	// raw is the token as written
+	raw string
+	// pos is the 1-based position of the token
+	pos int
+}
+
+// isWord reports whether r is part of words: field names, keywords, numbers and dates
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.:+-", r)
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, raw: "(", pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, raw: ")", pos: pos})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokComma, raw: ",", pos: pos})
+			i++
+		case r == '\'':
+			var b strings.Builder
+			j := i + 1
+			for {
+				if j >= len(runes) {
+					return nil, &Error{Param: "filter", Pos: pos, Token: string(runes[i:]), Reason: "unterminated string"}
+				}
+				if runes[j] == '\'' {
+					// a doubled quote is an escaped quote
+					if j+1 < len(runes) && runes[j+1] == '\'' {
+						b.WriteRune('\'')
+						j += 2
+						continue
+					}
+					break
+				}
+				b.WriteRune(runes[j])
+				j++
+			}
+			tokens = append(tokens, token{kind: tokString, text: b.String(), raw: string(runes[i : j+1]), pos: pos})
+			i = j + 1
+		case isWord(r):
+			j := i
+			for j < len(runes) && isWord(runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			tokens = append(tokens, token{kind: tokWord, text: word, raw: word, pos: pos})
+			i = j
+		default:
+			return nil, &Error{Param: "filter", Pos: pos, Token: string(r), Reason: fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	schema Schema
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the keyword kw, and consumes it if so
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokWord && strings.EqualFold(t.text, kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func errorAt(t token, reason string) *Error {
+	return &Error{Param: "filter", Pos: t.pos, Token: t.raw, Reason: reason}
+}
+
+// ParseFilter parses a filter expression against schema. An empty s returns a nil Expr.
+func ParseFilter(s string, schema Schema) (Expr, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	if len(s) > maxFilterLength {
+		return nil, &Error{Param: "filter", Pos: maxFilterLength + 1, Token: s[maxFilterLength:], Reason: fmt.Sprintf("filter longer than %d characters", maxFilterLength)}
+	}
+
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, schema: schema}
+
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorAt(t, fmt.Sprintf("unexpected %q, expected \"and\" or \"or\"", t.raw))
+	}
+
+	return e, nil
+}
+
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "or", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("and") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "and", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	t := p.peek()
+
+	if p.depth >= maxDepth {
+		return nil, errorAt(t, fmt.Sprintf("filter nested deeper than %d levels", maxDepth))
+	}
+
+	if p.keyword("not") {
+		p.depth++
+		e, err := p.unary()
+		p.depth--
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: e}, nil
+	}
+
+	if t.kind == tokLParen {
+		p.next()
+
+		p.depth++
+		e, err := p.or()
+		p.depth--
+		if err != nil {
+			return nil, err
+		}
+
+		if t := p.next(); t.kind != tokRParen {
+			return nil, errorAt(t, "expected \")\"")
+		}
+		return e, nil
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Expr, error) {
+	t := p.next()
+	if t.kind != tokWord {
+		return nil, errorAt(t, "expected field")
+	}
+
+	field, ok := p.schema[t.text]
+	if !ok {
+		return nil, errorAt(t, fmt.Sprintf("unknown field %q", t.text))
+	}
+
+	opToken := p.next()
+	if opToken.kind != tokWord {
+		return nil, errorAt(opToken, "expected operator")
+	}
+
+	// strings support every operator
+	op := Op(strings.ToLower(opToken.text))
+	if !hasOp(ops[String], op) {
+		return nil, errorAt(opToken, fmt.Sprintf("unknown operator %q", opToken.text))
+	}
+
+	if !field.allows(op) {
+		return nil, errorAt(opToken, fmt.Sprintf("operator %q is not allowed on field %q", op, t.text))
+	}
+
+	c := &Comparison{Field: t.text, Op: op}
+
+	if op != In {
+		v, err := p.value(&field)
+		if err != nil {
+			return nil, err
+		}
+		c.Values = []interface{}{v}
+		return c, nil
+	}
+
+	if t := p.next(); t.kind != tokLParen {
+		return nil, errorAt(t, "expected \"(\"")
+	}
+
+	for {
+		v, err := p.value(&field)
+		if err != nil {
+			return nil, err
+		}
+		c.Values = append(c.Values, v)
+
+		t := p.next()
+		if t.kind == tokRParen {
+			return c, nil
+		}
+		if t.kind != tokComma {
+			return nil, errorAt(t, "expected \",\" or \")\"")
+		}
+	}
+}
+
+// value parses a value of the Type of f
+func (p *parser) value(f *Field) (interface{}, error) {
+	t := p.next()
+
+	invalid := func() (interface{}, error) {
+		return nil, errorAt(t, fmt.Sprintf("expected %s value", f.Type))
+	}
+
+	if t.kind != tokWord && t.kind != tokString {
+		return invalid()
+	}
+
+	switch f.Type {
+	case String:
+		// strings must be quoted so they are never mistaken for keywords
+		if t.kind != tokString {
+			return invalid()
+		}
+		return t.text, nil
+	case Time:
+		if v, err := time.Parse(time.RFC3339, t.text); err == nil {
+			return v, nil
+		}
+		if v, err := time.Parse("2006-01-02", t.text); err == nil {
+			return v, nil
+		}
+		return invalid()
+	}
+
+	if t.kind != tokWord {
+		return invalid()
+	}
+
+	switch f.Type {
+	case Int:
+		if v, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return v, nil
+		}
+	case Float:
+		if v, err := strconv.ParseFloat(t.text, 64); err == nil {
+			return v, nil
+		}
+	case Bool:
+		switch strings.ToLower(t.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+
+	return invalid()
+}
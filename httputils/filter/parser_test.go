@@ -0,0 +1,225 @@
+package filter_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils/filter"
+)
+
+var schema = filter.Schema{
+	"status":     {Type: filter.String, Sortable: true},
+	"name":       {Type: filter.String, Column: "users.name", Ops: []filter.Op{filter.Eq, filter.Contains, filter.StartsWith}},
+	"age":        {Type: filter.Int},
+	"score":      {Type: filter.Float},
+	"active":     {Type: filter.Bool},
+	"created_at": {Type: filter.Time, Sortable: true},
+	"id":         {Type: filter.Int, Sortable: true},
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   filter.Expr
+	}{
+		{
+			name:   "empty",
+			filter: " ",
+			want:   nil,
+		},
+		{
+			name:   "comparison",
+			filter: "status eq 'active'",
+			want:   &filter.Comparison{Field: "status", Op: filter.Eq, Values: []interface{}{"active"}},
+		},
+		{
+			name:   "escaped quote",
+			filter: "name EQ 'O''Brien'",
+			want:   &filter.Comparison{Field: "name", Op: filter.Eq, Values: []interface{}{"O'Brien"}},
+		},
+		{
+			name:   "typed values",
+			filter: "age ge -3 and score lt 1.5 and active eq TRUE",
+			want: &filter.Logical{
+				Op: "and",
+				Left: &filter.Logical{
+					Op:    "and",
+					Left:  &filter.Comparison{Field: "age", Op: filter.Ge, Values: []interface{}{int64(-3)}},
+					Right: &filter.Comparison{Field: "score", Op: filter.Lt, Values: []interface{}{1.5}},
+				},
+				Right: &filter.Comparison{Field: "active", Op: filter.Eq, Values: []interface{}{true}},
+			},
+		},
+		{
+			name:   "dates",
+			filter: "created_at gt 2024-01-01 and created_at lt '2024-02-01T10:00:00Z'",
+			want: &filter.Logical{
+				Op:    "and",
+				Left:  &filter.Comparison{Field: "created_at", Op: filter.Gt, Values: []interface{}{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}},
+				Right: &filter.Comparison{Field: "created_at", Op: filter.Lt, Values: []interface{}{time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)}},
+			},
+		},
+		{
+			name:   "precedence",
+			filter: "status eq 'a' or status eq 'b' and not (age in (1, 2))",
+			want: &filter.Logical{
+				Op:   "or",
+				Left: &filter.Comparison{Field: "status", Op: filter.Eq, Values: []interface{}{"a"}},
+				Right: &filter.Logical{
+					Op:    "and",
+					Left:  &filter.Comparison{Field: "status", Op: filter.Eq, Values: []interface{}{"b"}},
+					Right: &filter.Not{Expr: &filter.Comparison{Field: "age", Op: filter.In, Values: []interface{}{int64(1), int64(2)}}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filter.ParseFilter(tt.filter, schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %#v. got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    string
+		wantPos   int
+		wantToken string
+		wantMsg   string
+	}{
+		{
+			name:      "unknown field",
+			filter:    "status eq 'a' and password eq 'x'",
+			wantPos:   19,
+			wantToken: "password",
+			wantMsg:   `filter: unknown field "password" at position 19`,
+		},
+		{
+			name:      "unknown operator",
+			filter:    "age like 3",
+			wantPos:   5,
+			wantToken: "like",
+			wantMsg:   `filter: unknown operator "like" at position 5`,
+		},
+		{
+			name:      "operator not allowed on type",
+			filter:    "active gt true",
+			wantPos:   8,
+			wantToken: "gt",
+			wantMsg:   `filter: operator "gt" is not allowed on field "active" at position 8`,
+		},
+		{
+			name:      "operator not allowed on field",
+			filter:    "name ne 'x'",
+			wantPos:   6,
+			wantToken: "ne",
+			wantMsg:   `filter: operator "ne" is not allowed on field "name" at position 6`,
+		},
+		{
+			name:      "wrong type",
+			filter:    "age eq 'old'",
+			wantPos:   8,
+			wantToken: "'old'",
+			wantMsg:   `filter: expected integer value at position 8`,
+		},
+		{
+			name:      "unquoted string",
+			filter:    "status eq active",
+			wantPos:   11,
+			wantToken: "active",
+			wantMsg:   `filter: expected string value at position 11`,
+		},
+		{
+			name:      "invalid date",
+			filter:    "created_at gt 2024-13-01",
+			wantPos:   15,
+			wantToken: "2024-13-01",
+			wantMsg:   `filter: expected date value at position 15`,
+		},
+		{
+			name:      "unterminated string",
+			filter:    "status eq 'active",
+			wantPos:   11,
+			wantToken: "'active",
+			wantMsg:   `filter: unterminated string at position 11`,
+		},
+		{
+			name:      "unexpected character",
+			filter:    "age eq 1; drop table users",
+			wantPos:   9,
+			wantToken: ";",
+			wantMsg:   `filter: unexpected character ';' at position 9`,
+		},
+		{
+			name:      "missing value",
+			filter:    "age eq",
+			wantPos:   7,
+			wantToken: "",
+			wantMsg:   `filter: expected integer value at end of input`,
+		},
+		{
+			name:      "missing parenthesis",
+			filter:    "(age eq 1",
+			wantPos:   10,
+			wantToken: "",
+			wantMsg:   `filter: expected ")" at end of input`,
+		},
+		{
+			name:      "trailing token",
+			filter:    "age eq 1 age eq 2",
+			wantPos:   10,
+			wantToken: "age",
+			wantMsg:   `filter: unexpected "age", expected "and" or "or" at position 10`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := filter.ParseFilter(tt.filter, schema)
+
+			ferr, ok := err.(*filter.Error)
+			if !ok {
+				t.Fatalf("want *filter.Error. got %v", err)
+			}
+
+			if ferr.Pos != tt.wantPos {
+				t.Errorf("want position %d. got %d", tt.wantPos, ferr.Pos)
+			}
+
+			if ferr.Token != tt.wantToken {
+				t.Errorf("want token %q. got %q", tt.wantToken, ferr.Token)
+			}
+
+			if ferr.Message() != tt.wantMsg {
+				t.Errorf("want message %s. got %s", tt.wantMsg, ferr.Message())
+			}
+
+			if ferr.Status() != 400 || ferr.Code() != "invalid_filter" {
+				t.Errorf("want 400 invalid_filter. got %d %s", ferr.Status(), ferr.Code())
+			}
+		})
+	}
+}
+
+func TestParseFilterDepth(t *testing.T) {
+	f := ""
+	for i := 0; i < 40; i++ {
+		f += "not "
+	}
+	f += "age eq 1"
+
+	if _, err := filter.ParseFilter(f, schema); err == nil {
+		t.Errorf("want error. got nil")
+	}
+}
@@ -0,0 +1,90 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Placeholder returns the placeholder of the nth, 1-based, argument of a SQL statement
+type Placeholder func(n int) string
+
+var (
+	// Question is the "?" placeholder of MySQL and SQLite
+	Question Placeholder = func(int) string { return "?" }
+	// Dollar is the "$n" placeholder of PostgreSQL
+	Dollar Placeholder = func(n int) string { return "$" + strconv.Itoa(n) }
+)
+
+var comparisons = map[Op]string{
+	Eq: "=",
+	Ne: "<>",
+	Gt: ">",
+	Ge: ">=",
+	Lt: "<",
+	Le: "<=",
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns with "!", as backslashes are
+// escape characters in MySQL string literals but not in PostgreSQL ones
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+// Where returns the SQL condition of e, without the WHERE keyword, and args with its values appended.
+// Pass the arguments of the statement before the condition as args, so placeholders are numbered after them.
+func Where(e Expr, schema Schema, ph Placeholder, args []interface{}) (string, []interface{}) {
+	var b strings.Builder
+	args = where(&b, e, schema, ph, args)
+	return b.String(), args
+}
+
+func where(b *strings.Builder, e Expr, schema Schema, ph Placeholder, args []interface{}) []interface{} {
+	arg := func(v interface{}) {
+		args = append(args, v)
+		b.WriteString(ph(len(args)))
+	}
+
+	switch e := e.(type) {
+	case *Logical:
+		b.WriteString("(")
+		args = where(b, e.Left, schema, ph, args)
+		b.WriteString(" " + strings.ToUpper(e.Op) + " ")
+		args = where(b, e.Right, schema, ph, args)
+		b.WriteString(")")
+	case *Not:
+		b.WriteString("NOT ")
+		// logical expressions are already parenthesized
+		if _, ok := e.Expr.(*Comparison); ok {
+			b.WriteString("(")
+			args = where(b, e.Expr, schema, ph, args)
+			b.WriteString(")")
+		} else {
+			args = where(b, e.Expr, schema, ph, args)
+		}
+	case *Comparison:
+		b.WriteString(schema.column(e.Field))
+
+		switch e.Op {
+		case In:
+			b.WriteString(" IN (")
+			for i, v := range e.Values {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				arg(v)
+			}
+			b.WriteString(")")
+		case Contains:
+			b.WriteString(" LIKE ")
+			arg("%" + likeEscaper.Replace(e.Values[0].(string)) + "%")
+			b.WriteString(" ESCAPE '!'")
+		case StartsWith:
+			b.WriteString(" LIKE ")
+			arg(likeEscaper.Replace(e.Values[0].(string)) + "%")
+			b.WriteString(" ESCAPE '!'")
+		default:
+			b.WriteString(" " + comparisons[e.Op] + " ")
+			arg(e.Values[0])
+		}
+	}
+
+	return args
+}
@@ -0,0 +1,75 @@
+package filter_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils/filter"
+)
+
+func TestWhere(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		ph       filter.Placeholder
+		args     []interface{}
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "question",
+			filter:   "status eq 'active' and age gt 30",
+			ph:       filter.Question,
+			wantSQL:  "(status = ? AND age > ?)",
+			wantArgs: []interface{}{"active", int64(30)},
+		},
+		{
+			name:     "dollar after args",
+			filter:   "status ne 'x' or age in (1, 2)",
+			ph:       filter.Dollar,
+			args:     []interface{}{"tenant"},
+			wantSQL:  "(status <> $2 OR age IN ($3, $4))",
+			wantArgs: []interface{}{"tenant", "x", int64(1), int64(2)},
+		},
+		{
+			name:     "not",
+			filter:   "not (age le 1 or age ge 9) and not active eq true",
+			ph:       filter.Question,
+			wantSQL:  "(NOT (age <= ? OR age >= ?) AND NOT (active = ?))",
+			wantArgs: []interface{}{int64(1), int64(9), true},
+		},
+		{
+			name:     "like",
+			filter:   "name contains '50%_off!' or name startswith 'a'",
+			ph:       filter.Dollar,
+			wantSQL:  "(users.name LIKE $1 ESCAPE '!' OR users.name LIKE $2 ESCAPE '!')",
+			wantArgs: []interface{}{"%50!%!_off!!%", "a%"},
+		},
+		{
+			name:     "injection",
+			filter:   "status eq ''' OR 1=1 --'",
+			ph:       filter.Question,
+			wantSQL:  "status = ?",
+			wantArgs: []interface{}{"' OR 1=1 --"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := filter.ParseFilter(tt.filter, schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sql, args := filter.Where(e, schema, tt.ph, tt.args)
+
+			if sql != tt.wantSQL {
+				t.Errorf("want %s. got %s", tt.wantSQL, sql)
+			}
+
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("want args %v. got %v", tt.wantArgs, args)
+			}
+		})
+	}
+}
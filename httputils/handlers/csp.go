@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/gddo/httputil/header"
+
+	"github.com/viaduct-ai/vgo/log"
+)
+
+// maxReportSize bounds the size of violation reports read
+const maxReportSize = 64 * 1024
+
+// cspReport is a report of the legacy report-uri directive, sent as application/csp-report
+type cspReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		Referrer           string `json:"referrer"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		BlockedURI         string `json:"blocked-uri"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		Disposition        string `json:"disposition"`
+	} `json:"csp-report"`
+}
+
+// reportingReport is a report of the Reporting API report-to directive, sent as application/reports+json
+type reportingReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		Referrer           string `json:"referrer"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		BlockedURL         string `json:"blockedURL"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Disposition        string `json:"disposition"`
+	} `json:"body"`
+}
+
+// CSPReport returns a handler receiving Content-Security-Policy violation reports, to be set as the
+// report-uri or report-to endpoint of the policy. Violations are logged through l.
+func CSPReport(l log.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxReportSize))
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		contentType, _ := header.ParseValueAndParams(r.Header, "Content-Type")
+
+		var violations [][]log.Field
+		switch contentType {
+		case "application/reports+json":
+			var reports []reportingReport
+			if err := json.Unmarshal(body, &reports); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			for _, report := range reports {
+				if report.Type != "csp-violation" {
+					continue
+				}
+
+				violations = append(violations, []log.Field{
+					log.String("document_uri", report.Body.DocumentURL),
+					log.String("referrer", report.Body.Referrer),
+					log.String("directive", report.Body.EffectiveDirective),
+					log.String("blocked_uri", report.Body.BlockedURL),
+					log.String("source_file", report.Body.SourceFile),
+					log.Int("line_number", report.Body.LineNumber),
+					log.String("disposition", report.Body.Disposition),
+				})
+			}
+
+		default:
+			var report cspReport
+			if err := json.Unmarshal(body, &report); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			directive := report.Report.EffectiveDirective
+			if directive == "" {
+				directive = report.Report.ViolatedDirective
+			}
+
+			violations = append(violations, []log.Field{
+				log.String("document_uri", report.Report.DocumentURI),
+				log.String("referrer", report.Report.Referrer),
+				log.String("directive", directive),
+				log.String("blocked_uri", report.Report.BlockedURI),
+				log.String("source_file", report.Report.SourceFile),
+				log.Int("line_number", report.Report.LineNumber),
+				log.String("disposition", report.Report.Disposition),
+			})
+		}
+
+		for _, fields := range violations {
+			log.InfoFields(l, "csp violation", fields...)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
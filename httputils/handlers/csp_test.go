@@ -0,0 +1,83 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils/handlers"
+	"github.com/viaduct-ai/vgo/testutils"
+)
+
+func TestCSPReport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		contentType   string
+		body          string
+		wantStatus    int
+		wantLogs      int
+		wantDirective string
+	}{
+		{
+			name:        "Report URI",
+			contentType: "application/csp-report",
+			body: `{"csp-report": {
+				"document-uri": "https://app.viaduct.ai/",
+				"violated-directive": "script-src-elem",
+				"effective-directive": "script-src-elem",
+				"blocked-uri": "https://evil.com/x.js",
+				"disposition": "enforce"
+			}}`,
+			wantStatus:    http.StatusNoContent,
+			wantLogs:      1,
+			wantDirective: "script-src-elem",
+		},
+		{
+			name:        "Report To",
+			contentType: "application/reports+json",
+			body: `[
+				{"type": "csp-violation", "body": {"documentURL": "https://app.viaduct.ai/", "effectiveDirective": "img-src", "blockedURL": "https://evil.com/x.png"}},
+				{"type": "deprecation", "body": {}}
+			]`,
+			wantStatus:    http.StatusNoContent,
+			wantLogs:      1,
+			wantDirective: "img-src",
+		},
+		{
+			name:        "Invalid",
+			contentType: "application/csp-report",
+			body:        `{`,
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			logger := testutils.NewTestLogger()
+
+			req := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			rr := httptest.NewRecorder()
+			handlers.CSPReport(logger)(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("want status code %d. got %d", tt.wantStatus, rr.Code)
+			}
+
+			if len(logger.InfoLogs) != tt.wantLogs {
+				t.Errorf("want %d logs. got %v", tt.wantLogs, logger.InfoLogs)
+			}
+
+			if tt.wantLogs > 0 && logger.Context["directive"] != tt.wantDirective {
+				t.Errorf("want directive %s. got %v", tt.wantDirective, logger.Context["directive"])
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+// Package idempotency makes retried requests safe with the Idempotency-Key header.
+//
+// The first response to a request with a given key is stored, per caller, and replayed for
+// retries of the same request. Retries with a different body are rejected with ErrKeyReused,
+// and retries arriving while the first request is still in flight with ErrInFlight.
+// Requests of unknown callers and requests with bodies larger than 1MB are not deduplicated.
+// https://datatracker.ietf.org/doc/draft-ietf-httpapi-idempotency-key-header/
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/jwtutils"
+	"github.com/viaduct-ai/vgo/log"
+)
+
+const (
+	oneMB = 1048576
+
+	// maxKeyLength bounds the size of keys stored
+	maxKeyLength = 255
+)
+
+var (
+	// KeyHeader is the request header holding the idempotency key
+	KeyHeader = http.CanonicalHeaderKey("Idempotency-Key")
+	// ReplayedHeader is set to "true" on replayed responses
+	ReplayedHeader = http.CanonicalHeaderKey("Idempotent-Replayed")
+)
+
+// Error is an APIError returned for requests that cannot be processed with their idempotency key
+type Error struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// Status returns the status code of the error
+func (e *Error) Status() int {
+	return e.status
+}
+
+// Message returns the message of the error
+func (e *Error) Message() string {
+	return e.msg
+}
+
+// Code returns the code of the error
+func (e *Error) Code() string {
+	return e.code
+}
+
+var (
+	// ErrInvalidKey is returned for keys longer than 255 characters
+	ErrInvalidKey = &Error{
+		status: http.StatusBadRequest,
+		code:   "invalid_idempotency_key",
+		msg:    "Idempotency-Key header must not be longer than 255 characters",
+	}
+	// ErrKeyReused is returned for a key already used with a different request
+	ErrKeyReused = &Error{
+		status: http.StatusUnprocessableEntity,
+		code:   "idempotency_key_reused",
+		msg:    "Idempotency-Key was already used with a different request",
+	}
+	// ErrInFlight is returned while the first request with a key is being processed
+	ErrInFlight = &Error{
+		status: http.StatusConflict,
+		code:   "idempotency_key_in_flight",
+		msg:    "a request with this Idempotency-Key is being processed, retry later",
+	}
+)
+
+// Record is the stored state of a key
+type Record struct {
+	// Fingerprint identifies the request, so a key reused with a different request is detected
+	Fingerprint string
+	// Completed is set once the response is stored
+	Completed bool
+	Status    int
+	Header    http.Header
+	Body      []byte
+}
+
+// Store keeps Records. Implement it with a shared store, such as SQL or Redis, to
+// deduplicate requests across instances.
+type Store interface {
+	// Reserve atomically saves an in-flight Record with fingerprint for key and returns true,
+	// unless there already is one, which it returns with false.
+	// Records expire after ttl.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, bool, error)
+	// Complete replaces the Record of key with rec
+	Complete(ctx context.Context, key string, rec *Record, ttl time.Duration) error
+	// Delete removes the Record of key, so the request can be retried
+	Delete(ctx context.Context, key string) error
+}
+
+// Config configures idempotency middleware
+type Config struct {
+	// Store defaults to a MemoryStore
+	Store Store
+	// Caller identifies the caller, so keys of different callers do not collide.
+	// Defaults to the unverified JWT subject. Requests with an empty caller are processed without
+	// deduplication, as anonymous callers would share keys; identify them, for example by IP address,
+	// to deduplicate their requests.
+	Caller func(r *http.Request) string
+	// TTL is how long responses are kept. Defaults to 24 hours.
+	TTL time.Duration
+	// Methods are the methods keys are honored for. Defaults to POST and PATCH.
+	Methods []string
+	// Logger logs Store errors, if set. Requests are processed without deduplication when the Store fails.
+	Logger log.Logger
+
+	once sync.Once
+}
+
+// Serve serves r with next, or replays the response stored for its idempotency key.
+// Responses with a 5xx status are not stored, so the request can be retried.
+// Only the headers next sets are stored, not those set by outer middleware, such as a request ID.
+func (c *Config) Serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	key := r.Header.Get(KeyHeader)
+	if key == "" || !c.honors(r.Method) {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if len(key) > maxKeyLength {
+		httputils.ServeError(w, ErrInvalidKey)
+		return
+	}
+
+	caller := c.caller(r)
+	if caller == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, oneMB+1))
+	if err != nil || len(body) > oneMB {
+		// too large to fingerprint, or unreadable: let next handle the body, as read so far and the rest
+		r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		next.ServeHTTP(w, r)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	store := c.store()
+	ctx := r.Context()
+	key = storeKey(caller, key)
+	fingerprint := fingerprint(r, body)
+
+	rec, reserved, err := store.Reserve(ctx, key, fingerprint, c.ttl())
+	if err != nil {
+		c.logError("idempotency key reservation failed, processing request: %v", err)
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if !reserved {
+		switch {
+		case rec.Fingerprint != fingerprint:
+			httputils.ServeError(w, ErrKeyReused)
+		case !rec.Completed:
+			httputils.ServeError(w, ErrInFlight)
+		default:
+			replay(w, rec)
+		}
+		return
+	}
+
+	rw := &recorder{ResponseWriter: w, status: http.StatusOK}
+	outer := w.Header().Clone()
+
+	completed := false
+	defer func() {
+		if !completed {
+			// the handler panicked, let the request be retried
+			if err := store.Delete(context.Background(), key); err != nil {
+				c.logError("idempotency key deletion failed: %v", err)
+			}
+		}
+	}()
+
+	next.ServeHTTP(rw, r)
+	completed = true
+
+	if rw.status >= http.StatusInternalServerError {
+		if err := store.Delete(ctx, key); err != nil {
+			c.logError("idempotency key deletion failed: %v", err)
+		}
+		return
+	}
+
+	err = store.Complete(ctx, key, &Record{
+		Fingerprint: fingerprint,
+		Completed:   true,
+		Status:      rw.status,
+		Header:      handlerHeader(outer, w.Header()),
+		Body:        rw.body.Bytes(),
+	}, c.ttl())
+	if err != nil {
+		c.logError("idempotency response storage failed: %v", err)
+	}
+}
+
+func (c *Config) honors(method string) bool {
+	methods := c.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodPost, http.MethodPatch}
+	}
+
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) caller(r *http.Request) string {
+	if c.Caller != nil {
+		return c.Caller(r)
+	}
+
+	// try parse claims from request, ignore errors
+	claims, _ := jwtutils.ParseUnverifiedTokenClaimsFromRequest(r)
+	sub, _ := claims["sub"].(string)
+	return sub
+}
+
+func (c *Config) store() Store {
+	c.once.Do(func() {
+		if c.Store == nil {
+			c.Store = NewMemoryStore()
+		}
+	})
+
+	return c.Store
+}
+
+func (c *Config) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return 24 * time.Hour
+	}
+
+	return c.TTL
+}
+
+func (c *Config) logError(template string, err error) {
+	if c.Logger != nil {
+		c.Logger.Errorf(template, err)
+	}
+}
+
+// fingerprint hashes the method, path and body of r
+// storeKey scopes key to caller. The caller is length prefixed, as callers and keys may both contain ':'
+func storeKey(caller, key string) string {
+	return strconv.Itoa(len(caller)) + ":" + caller + ":" + key
+}
+
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{0})
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// handlerHeader returns the headers of after that differ from before, the headers set before the handler ran
+func handlerHeader(before, after http.Header) http.Header {
+	h := http.Header{}
+	for k, v := range after {
+		if !equal(before[k], v) {
+			h[k] = append([]string(nil), v...)
+		}
+	}
+
+	return h
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func replay(w http.ResponseWriter, rec *Record) {
+	h := w.Header()
+	for k, v := range rec.Header {
+		h[k] = v
+	}
+	h.Set(ReplayedHeader, "true")
+
+	w.WriteHeader(rec.Status)
+	w.Write(rec.Body)
+}
+
+// readCloser reads from Reader and closes Closer
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// recorder copies the status and body of a response
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recorder) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.status = status
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (w *recorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,311 @@
+package idempotency_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/idempotency"
+)
+
+// counter creates a resource per request and responds with its id
+type counter struct {
+	calls  int
+	status int
+}
+
+func (c *counter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.calls++
+
+	status := c.status
+	if status == 0 {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Location", "/v1/things/1")
+	httputils.ServeJSON(w, status, map[string]int{"id": c.calls})
+}
+
+func serve(cfg *idempotency.Config, next http.Handler, method, key, caller, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/v1/things", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	req.Header.Set("X-Caller", caller)
+
+	rr := httptest.NewRecorder()
+	cfg.Serve(rr, req, next)
+	return rr
+}
+
+func decodeError(t *testing.T, rr *httptest.ResponseRecorder) httputils.APIErrorResponse {
+	t.Helper()
+
+	var resp httputils.APIErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func newConfig() *idempotency.Config {
+	return &idempotency.Config{
+		Caller: func(r *http.Request) string {
+			return r.Header.Get("X-Caller")
+		},
+	}
+}
+
+func TestServeReplay(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	next := &counter{}
+
+	first := serve(cfg, next, http.MethodPost, "abc", "alice", `{"name": "thing"}`)
+	retry := serve(cfg, next, http.MethodPost, "abc", "alice", `{"name": "thing"}`)
+
+	if next.calls != 1 {
+		t.Fatalf("want handler to be called once. got %d", next.calls)
+	}
+
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("want replayed response %d %s. got %d %s", http.StatusCreated, first.Body, retry.Code, retry.Body)
+	}
+
+	if got := retry.Header().Get("Location"); got != "/v1/things/1" {
+		t.Errorf("want replayed Location header. got %q", got)
+	}
+
+	if got := retry.Header().Get("Idempotent-Replayed"); got != "true" {
+		t.Errorf("want Idempotent-Replayed true. got %q", got)
+	}
+
+	// keys are scoped per caller
+	serve(cfg, next, http.MethodPost, "abc", "bob", `{"name": "thing"}`)
+	if next.calls != 2 {
+		t.Errorf("want another caller's request to be processed. got %d calls", next.calls)
+	}
+}
+
+func TestServeCallerKeyCollision(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	next := &counter{}
+
+	serve(cfg, next, http.MethodPost, "c", "a:b", `{"name": "thing"}`)
+	other := serve(cfg, next, http.MethodPost, "b:c", "a", `{"name": "thing"}`)
+
+	if next.calls != 2 {
+		t.Errorf("want requests of callers %q and %q not to collide. got %d calls", "a:b", "a", next.calls)
+	}
+
+	if got := other.Header().Get("Idempotent-Replayed"); got != "" {
+		t.Errorf("want no Idempotent-Replayed header. got %q", got)
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	next := &counter{}
+
+	serve(cfg, next, http.MethodPost, "abc", "alice", `{"name": "thing"}`)
+
+	tests := []struct {
+		name       string
+		key        string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "Different Body",
+			key:        "abc",
+			body:       `{"name": "other"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "idempotency_key_reused",
+		},
+		{
+			name:       "Key Too Long",
+			key:        strings.Repeat("a", 256),
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_idempotency_key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serve(cfg, next, http.MethodPost, tt.key, "alice", tt.body)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("want status code %d. got %d", tt.wantStatus, rr.Code)
+			}
+
+			if got := decodeError(t, rr).Code; got != tt.wantCode {
+				t.Errorf("want code %s. got %s", tt.wantCode, got)
+			}
+		})
+	}
+}
+
+func TestServeInFlight(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+
+	var retry *httptest.ResponseRecorder
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the client retries while the first request is processed
+		retry = serve(cfg, &counter{}, http.MethodPost, "abc", "alice", "")
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	serve(cfg, next, http.MethodPost, "abc", "alice", "")
+
+	if retry.Code != http.StatusConflict {
+		t.Errorf("want status code %d. got %d", http.StatusConflict, retry.Code)
+	}
+}
+
+func TestServeNotStored(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		status int
+	}{
+		{
+			name:   "No Key",
+			method: http.MethodPost,
+		},
+		{
+			name:   "GET",
+			method: http.MethodGet,
+			key:    "abc",
+		},
+		{
+			name:   "Server Error",
+			method: http.MethodPost,
+			key:    "abc",
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newConfig()
+			next := &counter{status: tt.status}
+
+			serve(cfg, next, tt.method, tt.key, "alice", "")
+			serve(cfg, next, tt.method, tt.key, "alice", "")
+
+			if next.calls != 2 {
+				t.Errorf("want handler to be called twice. got %d", next.calls)
+			}
+		})
+	}
+}
+
+func TestServeBody(t *testing.T) {
+	t.Parallel()
+
+	var got string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		got = string(body)
+	})
+
+	serve(newConfig(), next, http.MethodPost, "abc", "alice", `{"name": "thing"}`)
+
+	if want := `{"name": "thing"}`; got != want {
+		t.Errorf("want body %s to reach the handler. got %s", want, got)
+	}
+}
+
+func TestServeUnknownCaller(t *testing.T) {
+	t.Parallel()
+
+	// the default caller is the JWT subject, and there is no JWT
+	cfg := &idempotency.Config{}
+	next := &counter{}
+
+	serve(cfg, next, http.MethodPost, "abc", "", `{"name": "thing"}`)
+	retry := serve(cfg, next, http.MethodPost, "abc", "", `{"name": "thing"}`)
+
+	if next.calls != 2 {
+		t.Errorf("want requests of unknown callers not to be deduplicated. got %d calls", next.calls)
+	}
+
+	if got := retry.Header().Get("Idempotent-Replayed"); got != "" {
+		t.Errorf("want no Idempotent-Replayed header. got %q", got)
+	}
+}
+
+func TestServeLargeBody(t *testing.T) {
+	t.Parallel()
+
+	body := `{"name": "` + strings.Repeat("a", 1<<20) + `"}`
+
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		got, err := ioutil.ReadAll(r.Body)
+		if err != nil || string(got) != body {
+			t.Errorf("want the whole body to reach the handler. got %d bytes, %v", len(got), err)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	cfg := newConfig()
+	for i := 0; i < 2; i++ {
+		rr := serve(cfg, next, http.MethodPost, "abc", "alice", body)
+		if rr.Code != http.StatusCreated {
+			t.Errorf("want status code %d. got %d", http.StatusCreated, rr.Code)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("want large requests not to be deduplicated. got %d calls", calls)
+	}
+}
+
+func TestServeReplayHandlerHeaders(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	next := &counter{}
+
+	serveWithRequestID := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/things", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		req.Header.Set("X-Caller", "alice")
+
+		rr := httptest.NewRecorder()
+		// as set by an outer RequestID middleware
+		rr.Header().Set("X-Request-Id", id)
+		cfg.Serve(rr, req, next)
+		return rr
+	}
+
+	serveWithRequestID("first")
+	retry := serveWithRequestID("retry")
+
+	if got := retry.Header().Get("X-Request-Id"); got != "retry" {
+		t.Errorf("want the request id of the retry. got %q", got)
+	}
+
+	if got := retry.Header().Get("Location"); got != "/v1/things/1" {
+		t.Errorf("want replayed Location header. got %q", got)
+	}
+}
@@ -0,0 +1,82 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxRecords bounds the number of keys kept before expired ones are pruned
+const maxRecords = 10000
+
+type entry struct {
+	rec     *Record
+	expires time.Time
+}
+
+// MemoryStore is an in-memory Store. Keys are only deduplicated per instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewMemoryStore returns a reference to a new MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: map[string]*entry{},
+	}
+}
+
+// Reserve saves an in-flight Record for key unless there is one
+func (s *MemoryStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Record, bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		return e.rec, false, nil
+	}
+
+	if len(s.entries) >= maxRecords {
+		s.prune(now)
+	}
+
+	s.entries[key] = &entry{
+		rec:     &Record{Fingerprint: fingerprint},
+		expires: now.Add(ttl),
+	}
+
+	return nil, true, nil
+}
+
+// Complete replaces the Record of key
+func (s *MemoryStore) Complete(ctx context.Context, key string, rec *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = &entry{
+		rec:     rec,
+		expires: time.Now().Add(ttl),
+	}
+
+	return nil
+}
+
+// Delete removes the Record of key
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// prune drops expired entries. s.mu must be held.
+func (s *MemoryStore) prune(now time.Time) {
+	for key, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package idempotency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils/idempotency"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := idempotency.NewMemoryStore()
+
+	if _, ok, _ := s.Reserve(ctx, "a", "fp", time.Hour); !ok {
+		t.Fatal("want key to be reserved")
+	}
+
+	rec, ok, _ := s.Reserve(ctx, "a", "other", time.Hour)
+	if ok || rec.Fingerprint != "fp" || rec.Completed {
+		t.Fatalf("want the in-flight record of fp. got %+v, %t", rec, ok)
+	}
+
+	s.Complete(ctx, "a", &idempotency.Record{Fingerprint: "fp", Completed: true, Status: 201}, time.Hour)
+
+	if rec, _, _ := s.Reserve(ctx, "a", "fp", time.Hour); rec.Status != 201 {
+		t.Errorf("want the completed record. got %+v", rec)
+	}
+
+	s.Delete(ctx, "a")
+
+	if _, ok, _ := s.Reserve(ctx, "a", "fp", time.Hour); !ok {
+		t.Errorf("want deleted key to be reserved again")
+	}
+
+	if _, ok, _ := s.Reserve(ctx, "b", "fp", time.Nanosecond); !ok {
+		t.Fatal("want key to be reserved")
+	}
+	time.Sleep(time.Millisecond)
+
+	if _, ok, _ := s.Reserve(ctx, "b", "fp", time.Hour); !ok {
+		t.Errorf("want expired key to be reserved again")
+	}
+}
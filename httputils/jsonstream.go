@@ -0,0 +1,371 @@
+package httputils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/golang/gddo/httputil/header"
+)
+
+// ContentTypeNDJSON is a constant for newline-delimited JSON Header Type
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// StreamOptions configures DecodeJSONStream
+type StreamOptions struct {
+	// MaxItemSize is the maximum size of an item in bytes. Defaults to 1MB.
+	MaxItemSize int
+	// MaxTotalSize is the maximum size of the request body in bytes. Defaults to 100MB.
+	MaxTotalSize int64
+	// MaxItems is the maximum number of items. Zero is unlimited.
+	MaxItems int
+	// CollectErrors continues with the next item when an item fails, collecting the errors in the StreamResult.
+	// By default, decoding stops at the first failed item, which is returned as the error.
+	CollectErrors bool
+}
+
+func (o *StreamOptions) maxItemSize() int {
+	if o.MaxItemSize <= 0 {
+		return oneMB
+	}
+	return o.MaxItemSize
+}
+
+func (o *StreamOptions) maxTotalSize() int64 {
+	if o.MaxTotalSize <= 0 {
+		return 100 * oneMB
+	}
+	return o.MaxTotalSize
+}
+
+// ItemError is an error decoding or handling an item of a JSON stream.
+// It is an APIError with the status, code and message of Err if Err is an APIError or is
+// classified by ServeError, such as context.Canceled, and an internal error otherwise.
+type ItemError struct {
+	// Index is the position of the item in the stream, starting at 0
+	Index int
+	Err   error
+}
+
+func (ie *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", ie.Index, ie.Err)
+}
+
+// Unwrap returns Err
+func (ie *ItemError) Unwrap() error {
+	return ie.Err
+}
+
+// apiError returns Err as an APIError, like ServeError, or nil if it is unknown
+func (ie *ItemError) apiError() APIError {
+	var apiError APIError
+	if errors.As(ie.Err, &apiError) {
+		return apiError
+	}
+	return mapError(ie.Err)
+}
+
+// Status returns the status of Err
+func (ie *ItemError) Status() int {
+	if apiError := ie.apiError(); apiError != nil {
+		return apiError.Status()
+	}
+	return http.StatusInternalServerError
+}
+
+// Message returns the message of Err, prefixed by the item index
+func (ie *ItemError) Message() string {
+	if apiError := ie.apiError(); apiError != nil {
+		return fmt.Sprintf("item %d: %s", ie.Index, apiError.Message())
+	}
+	return fmt.Sprintf("item %d: %s", ie.Index, internalError.Message)
+}
+
+// Code returns the code of Err
+func (ie *ItemError) Code() string {
+	if apiError := ie.apiError(); apiError != nil {
+		return apiError.Code()
+	}
+	return internalError.Code
+}
+
+// StreamResult is the outcome of DecodeJSONStream
+type StreamResult struct {
+	// Items is the number of items read, including failed ones
+	Items int
+	// Errors are the failed items, if StreamOptions.CollectErrors is set
+	Errors []*ItemError
+}
+
+// DecodeJSONStream reads the items of a newline-delimited JSON (application/x-ndjson) or
+// JSON array (application/json) request body one at a time, without reading the whole body in memory.
+//
+// handle is called for every item with its index and a function strictly decoding the item into
+// a destination, like DecodeJSONBody. Errors returned by handle, including decoding errors, fail the item.
+// Failed items stop decoding unless opts.CollectErrors is set; their errors are *ItemError.
+//
+// A malformed JSON array, data after the array, or a request body exceeding opts.MaxTotalSize, stops decoding
+// of either kind. Malformed lines of NDJSON fail their item only. Array items exceeding opts.MaxItemSize by
+// more than a few separators and whitespace stop decoding as well, as they are not read in memory to skip them.
+func DecodeJSONStream(w http.ResponseWriter, r *http.Request, opts StreamOptions, handle func(index int, decode func(dst interface{}) error) error) (*StreamResult, error) {
+	value, _ := header.ParseValueAndParams(r.Header, ContentType)
+
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, opts.maxTotalSize()))
+
+	var ndjson bool
+	switch value {
+	case ContentTypeNDJSON, "application/jsonl":
+		ndjson = true
+	case ContentTypeJSON:
+	case "":
+		// sniff the first non-whitespace byte: an array or NDJSON
+		b, err := peekNonSpace(body)
+		if err != nil && err != io.EOF {
+			return nil, streamError(err, 0)
+		}
+		ndjson = b != '['
+	default:
+		msg := fmt.Sprintf("%s header is not %s or %s", ContentType, ContentTypeNDJSON, ContentTypeJSON)
+		return nil, &malformedRequest{status: http.StatusUnsupportedMediaType, msg: msg}
+	}
+
+	d := &streamDecoder{opts: opts, handle: handle, result: &StreamResult{}}
+
+	var err error
+	if ndjson {
+		err = d.decodeNDJSON(body)
+	} else {
+		err = d.decodeArray(body)
+	}
+
+	return d.result, err
+}
+
+type streamDecoder struct {
+	opts   StreamOptions
+	handle func(index int, decode func(dst interface{}) error) error
+	result *StreamResult
+}
+
+// item handles an item. It returns an error if decoding must stop.
+func (d *streamDecoder) item(raw []byte, err error) error {
+	index := d.result.Items
+	d.result.Items++
+
+	if d.opts.MaxItems > 0 && d.result.Items > d.opts.MaxItems {
+		msg := fmt.Sprintf("request body must not contain more than %d items", d.opts.MaxItems)
+		return &malformedRequest{status: http.StatusRequestEntityTooLarge, msg: msg}
+	}
+
+	if err == nil {
+		err = d.handle(index, func(dst interface{}) error {
+			return decodeItem(raw, dst)
+		})
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	ie := &ItemError{Index: index, Err: err}
+	if !d.opts.CollectErrors {
+		return ie
+	}
+
+	d.result.Errors = append(d.result.Errors, ie)
+	return nil
+}
+
+func (d *streamDecoder) decodeNDJSON(body *bufio.Reader) error {
+	max := d.opts.maxItemSize()
+
+	for {
+		line, tooLarge, err := readLine(body, max)
+		if err != nil && err != io.EOF {
+			return streamError(err, d.result.Items)
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 || tooLarge {
+			var itemErr error
+			if tooLarge {
+				itemErr = valueTooLarge(max)
+			}
+
+			if err := d.item(line, itemErr); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func (d *streamDecoder) decodeArray(body *bufio.Reader) error {
+	max := d.opts.maxItemSize()
+
+	// the decoder reads ahead, so the item size is checked when reading as well as once decoded,
+	// allowing for separators and whitespace, to bound the memory of an item
+	r := &itemReader{r: body, n: int64(max) + itemSlack}
+	dec := json.NewDecoder(r)
+
+	t, err := dec.Token()
+	if err == io.EOF {
+		msg := "request body must not be empty"
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+	if err != nil {
+		return streamError(err, 0)
+	}
+
+	if delim, ok := t.(json.Delim); !ok || delim != '[' {
+		msg := "request body must be a JSON array"
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	for {
+		r.n = int64(max) + itemSlack
+		if !dec.More() {
+			break
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if err == errItemTooLarge {
+				// the decoder can't skip to the next item, so decoding stops even if errors are collected
+				ie := &ItemError{Index: d.result.Items, Err: valueTooLarge(max)}
+				d.result.Items++
+				return ie
+			}
+			return streamError(err, d.result.Items)
+		}
+
+		var itemErr error
+		if len(raw) > max {
+			itemErr = valueTooLarge(max)
+		}
+
+		if err := d.item(raw, itemErr); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return streamError(err, d.result.Items)
+	}
+
+	r.n = itemSlack
+	if _, err := dec.Token(); err != io.EOF {
+		msg := "request body must only contain a single JSON array"
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	return nil
+}
+
+// itemSlack is the size allowed for the separators and whitespace around an item of a JSON array
+const itemSlack = 1024
+
+var errItemTooLarge = errors.New("item too large")
+
+// itemReader fails with errItemTooLarge once n bytes have been read
+type itemReader struct {
+	r io.Reader
+	n int64
+}
+
+func (ir *itemReader) Read(p []byte) (int, error) {
+	if ir.n <= 0 {
+		return 0, errItemTooLarge
+	}
+
+	if int64(len(p)) > ir.n {
+		p = p[:ir.n]
+	}
+
+	n, err := ir.r.Read(p)
+	ir.n -= int64(n)
+	return n, err
+}
+
+func valueTooLarge(max int) error {
+	msg := fmt.Sprintf("value must not be larger than %d bytes", max)
+	return &malformedRequest{status: http.StatusRequestEntityTooLarge, msg: msg}
+}
+
+// decodeItem strictly decodes a single JSON value
+func decodeItem(raw []byte, dst interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		if msg, ok := jsonErrorMessage("value", err); ok {
+			return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
+		return err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		msg := "value must only contain a single JSON value"
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	return nil
+}
+
+// streamError describes an error reading the stream at the item index, which stops decoding
+func streamError(err error, index int) error {
+	if isBodyTooLarge(err) {
+		return &malformedRequest{status: http.StatusRequestEntityTooLarge, msg: msgRequestTooLarge}
+	}
+
+	if msg, ok := jsonErrorMessage(fmt.Sprintf("request body item %d", index), err); ok {
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	return err
+}
+
+// readLine reads a line of at most max bytes. Longer lines are discarded and reported as tooLarge.
+func readLine(r *bufio.Reader, max int) (line []byte, tooLarge bool, err error) {
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !tooLarge {
+			size := len(line) + len(bytes.TrimRight(chunk, "\n"))
+			if size > max {
+				tooLarge = true
+				line = nil
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+
+		return bytes.TrimRight(line, "\r\n"), tooLarge, err
+	}
+}
+
+// peekNonSpace returns the first non-whitespace byte of r without consuming it
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+
+		return b, r.UnreadByte()
+	}
+}
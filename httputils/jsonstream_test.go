@@ -0,0 +1,252 @@
+package httputils_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils"
+)
+
+type streamItem struct {
+	Name string `json:"name"`
+}
+
+func TestDecodeJSONStream(t *testing.T) {
+	t.Parallel()
+
+	errRejected := errors.New("rejected")
+
+	tests := []struct {
+		name          string
+		contentType   string
+		body          string
+		opts          httputils.StreamOptions
+		reject        string
+		wantNames     []string
+		wantItems     int
+		wantItemErrs  []int
+		wantErrStatus int
+		wantErrIndex  int
+	}{
+		{
+			name:        "NDJSON",
+			contentType: httputils.ContentTypeNDJSON,
+			body:        "{\"name\": \"a\"}\n\n{\"name\": \"b\"}\r\n{\"name\": \"c\"}",
+			wantNames:   []string{"a", "b", "c"},
+			wantItems:   3,
+		},
+		{
+			name:        "Array",
+			contentType: httputils.ContentTypeJSON,
+			body:        `[{"name": "a"}, {"name": "b"}]`,
+			wantNames:   []string{"a", "b"},
+			wantItems:   2,
+		},
+		{
+			name:      "Sniffed Array",
+			body:      ` [{"name": "a"}]`,
+			wantNames: []string{"a"},
+			wantItems: 1,
+		},
+		{
+			name:      "Sniffed NDJSON",
+			body:      `{"name": "a"}`,
+			wantNames: []string{"a"},
+			wantItems: 1,
+		},
+		{
+			name:          "Fail Fast",
+			contentType:   httputils.ContentTypeNDJSON,
+			body:          "{\"name\": \"a\"}\n{\"name\": \"b\", \"color\": \"red\"}\n{\"name\": \"c\"}",
+			wantNames:     []string{"a"},
+			wantItems:     2,
+			wantErrStatus: http.StatusBadRequest,
+			wantErrIndex:  1,
+		},
+		{
+			name:         "Collect Errors",
+			contentType:  httputils.ContentTypeNDJSON,
+			body:         "{\"name\": \"a\"}\n{\"name\": 1}\n{bad\n{\"name\": \"" + strings.Repeat("x", 64) + "\"}\n{\"name\": \"b\"}\n{\"name\": \"c\"}",
+			opts:         httputils.StreamOptions{CollectErrors: true, MaxItemSize: 32},
+			reject:       "b",
+			wantNames:    []string{"a", "c"},
+			wantItems:    6,
+			wantItemErrs: []int{1, 2, 3, 4},
+		},
+		{
+			name:          "Array Handler Error",
+			contentType:   httputils.ContentTypeJSON,
+			body:          `[{"name": "a"}, {"name": "b"}]`,
+			reject:        "b",
+			wantNames:     []string{"a"},
+			wantItems:     2,
+			wantErrStatus: http.StatusInternalServerError,
+			wantErrIndex:  1,
+		},
+		{
+			name:          "Malformed Array",
+			contentType:   httputils.ContentTypeJSON,
+			body:          `[{"name": "a"}, {"name": }]`,
+			opts:          httputils.StreamOptions{CollectErrors: true},
+			wantNames:     []string{"a"},
+			wantItems:     1,
+			wantErrStatus: http.StatusBadRequest,
+		},
+		{
+			name:          "Array Item Too Large",
+			contentType:   httputils.ContentTypeJSON,
+			body:          `[{"name": "a"}, {"name": "` + strings.Repeat("x", 4096) + `"}, {"name": "c"}]`,
+			opts:          httputils.StreamOptions{CollectErrors: true, MaxItemSize: 32},
+			wantNames:     []string{"a"},
+			wantItems:     2,
+			wantErrStatus: http.StatusRequestEntityTooLarge,
+			wantErrIndex:  1,
+		},
+		{
+			name:         "Array Item Slightly Too Large",
+			contentType:  httputils.ContentTypeJSON,
+			body:         `[{"name": "a"}, {"name": "` + strings.Repeat("x", 64) + `"}, {"name": "c"}]`,
+			opts:         httputils.StreamOptions{CollectErrors: true, MaxItemSize: 32},
+			wantNames:    []string{"a", "c"},
+			wantItems:    3,
+			wantItemErrs: []int{1},
+		},
+		{
+			name:          "Array Trailing Data",
+			contentType:   httputils.ContentTypeJSON,
+			body:          `[{"name": "a"}]garbage`,
+			wantNames:     []string{"a"},
+			wantItems:     1,
+			wantErrStatus: http.StatusBadRequest,
+		},
+		{
+			name:          "Sniffed Array Trailing Array",
+			body:          `[{"name": "a"}] [{"name": "b"}]`,
+			wantNames:     []string{"a"},
+			wantItems:     1,
+			wantErrStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "Array Trailing Whitespace",
+			contentType: httputils.ContentTypeJSON,
+			body:        "[{\"name\": \"a\"}]\n",
+			wantNames:   []string{"a"},
+			wantItems:   1,
+		},
+		{
+			name:          "Not An Array",
+			contentType:   httputils.ContentTypeJSON,
+			body:          `{"name": "a"}`,
+			wantItems:     0,
+			wantErrStatus: http.StatusBadRequest,
+		},
+		{
+			name:          "Too Many Items",
+			contentType:   httputils.ContentTypeJSON,
+			body:          `[{"name": "a"}, {"name": "b"}]`,
+			opts:          httputils.StreamOptions{MaxItems: 1},
+			wantNames:     []string{"a"},
+			wantItems:     2,
+			wantErrStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:          "Too Large",
+			contentType:   httputils.ContentTypeNDJSON,
+			body:          "{\"name\": \"a\"}\n{\"name\": \"b\"}\n",
+			opts:          httputils.StreamOptions{MaxTotalSize: 20},
+			wantNames:     []string{"a"},
+			wantItems:     1,
+			wantErrStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:          "Unsupported",
+			contentType:   "text/csv",
+			body:          "a",
+			wantErrStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			var names []string
+			res, err := httputils.DecodeJSONStream(httptest.NewRecorder(), req, tt.opts, func(i int, decode func(interface{}) error) error {
+				var item streamItem
+				if err := decode(&item); err != nil {
+					return err
+				}
+
+				if item.Name == tt.reject {
+					return errRejected
+				}
+
+				names = append(names, item.Name)
+				return nil
+			})
+
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("want items %v. got %v", tt.wantNames, names)
+			}
+
+			if tt.wantErrStatus != 0 {
+				var apiError httputils.APIError
+				if !errors.As(err, &apiError) || apiError.Status() != tt.wantErrStatus {
+					t.Fatalf("want APIError with status code %d. got %v", tt.wantErrStatus, err)
+				}
+
+				var itemError *httputils.ItemError
+				if errors.As(err, &itemError) && itemError.Index != tt.wantErrIndex {
+					t.Errorf("want error at item %d. got %d", tt.wantErrIndex, itemError.Index)
+				}
+			} else if err != nil {
+				t.Fatalf("want no error. got %v", err)
+			}
+
+			if res == nil {
+				return
+			}
+
+			if res.Items != tt.wantItems {
+				t.Errorf("want %d items. got %d", tt.wantItems, res.Items)
+			}
+
+			var gotItemErrs []int
+			for _, ie := range res.Errors {
+				gotItemErrs = append(gotItemErrs, ie.Index)
+			}
+
+			if !reflect.DeepEqual(gotItemErrs, tt.wantItemErrs) {
+				t.Errorf("want failed items %v. got %v", tt.wantItemErrs, gotItemErrs)
+			}
+		})
+	}
+}
+
+func TestItemError(t *testing.T) {
+	t.Parallel()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}\n{\"color\": \"red\"}"))
+
+	_, err := httputils.DecodeJSONStream(rr, req, httputils.StreamOptions{}, func(i int, decode func(interface{}) error) error {
+		return decode(&streamItem{})
+	})
+
+	httputils.ServeError(rr, err)
+
+	want := `{"Message":"item 1: value contains unknown field \"color\"","Code":"bad_request"}`
+	if got := rr.Body.String(); got != want {
+		t.Errorf("want body %s. got %s", want, got)
+	}
+}
@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/viaduct-ai/vgo/httputils/accesslog"
+	"github.com/viaduct-ai/vgo/log"
+	"github.com/viaduct-ai/vgo/reporting"
+)
+
+// Middleware wraps an http.Handler with additional behavior.
+// It is compatible with gorilla/handlers, Alice and ginutils.WrapMiddleware.
+type Middleware func(http.Handler) http.Handler
+
+// Chain is an immutable list of Middleware. The first Middleware is the outermost.
+// Inspired by https://github.com/justinas/alice
+type Chain struct {
+	middlewares []Middleware
+}
+
+// NewChain creates a Chain of middlewares
+func NewChain(middlewares ...Middleware) Chain {
+	return Chain{}.Append(middlewares...)
+}
+
+// Append returns a new Chain with middlewares added after the existing ones
+func (c Chain) Append(middlewares ...Middleware) Chain {
+	m := make([]Middleware, 0, len(c.middlewares)+len(middlewares))
+	m = append(m, c.middlewares...)
+	m = append(m, middlewares...)
+
+	return Chain{middlewares: m}
+}
+
+// Extend returns a new Chain with the middlewares of other added after the existing ones
+func (c Chain) Extend(other Chain) Chain {
+	return c.Append(other.middlewares...)
+}
+
+// Then wraps h with every Middleware of the Chain.
+// A nil h is replaced with http.DefaultServeMux.
+func (c Chain) Then(h http.Handler) http.Handler {
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+
+	return h
+}
+
+// ThenFunc wraps fn with every Middleware of the Chain.
+// A nil fn is replaced with http.DefaultServeMux.
+func (c Chain) ThenFunc(fn http.HandlerFunc) http.Handler {
+	if fn == nil {
+		return c.Then(nil)
+	}
+
+	return c.Then(fn)
+}
+
+// Logging returns LoggingMiddleware as a Middleware
+func Logging(l log.Logger, opts ...accesslog.Option) Middleware {
+	return func(next http.Handler) http.Handler {
+		return LoggingMiddleware(l, next, opts...)
+	}
+}
+
+// Recovery returns RecoveryMiddleware as a Middleware
+func Recovery(l log.Logger, r reporting.Reporter) Middleware {
+	return func(next http.Handler) http.Handler {
+		return RecoveryMiddleware(l, r, next)
+	}
+}
+
+// DefaultChain returns the standard vgo middleware stack: request ID, metrics, recovery and logging, outermost first.
+// Metrics wraps recovery, so panics are recorded as the 500 responses they are served as.
+// r and m may be nil, in which case panics are not reported and metrics are not recorded.
+func DefaultChain(l log.Logger, r reporting.Reporter, m MetricsRecorder, opts ...accesslog.Option) Chain {
+	c := NewChain(RequestID())
+
+	if m != nil {
+		c = c.Append(Metrics(m))
+	}
+
+	return c.Append(
+		Recovery(l, r),
+		Logging(l, opts...),
+	)
+}
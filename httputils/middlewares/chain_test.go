@@ -0,0 +1,112 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils/middlewares"
+	"github.com/viaduct-ai/vgo/reporting"
+	"github.com/viaduct-ai/vgo/testutils"
+)
+
+// tagMiddleware appends tag to the X-Order response header before calling next
+func tagMiddleware(tag string) middlewares.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Order", tag)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	base := middlewares.NewChain(tagMiddleware("1"), tagMiddleware("2"))
+	appended := base.Append(tagMiddleware("3"))
+	extended := base.Extend(middlewares.NewChain(tagMiddleware("4")))
+
+	tests := []struct {
+		name      string
+		chain     middlewares.Chain
+		wantOrder []string
+	}{
+		{
+			name:      "Empty",
+			chain:     middlewares.NewChain(),
+			wantOrder: nil,
+		},
+		{
+			name:      "Order",
+			chain:     base,
+			wantOrder: []string{"1", "2"},
+		},
+		{
+			name:      "Append",
+			chain:     appended,
+			wantOrder: []string{"1", "2", "3"},
+		},
+		{
+			name:      "Extend",
+			chain:     extended,
+			wantOrder: []string{"1", "2", "4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.chain.ThenFunc(dummyHandler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			got := rr.Header()["X-Order"]
+			if !reflect.DeepEqual(tt.wantOrder, got) {
+				t.Errorf("want order %v. got %v", tt.wantOrder, got)
+			}
+		})
+	}
+}
+
+func TestDefaultChain(t *testing.T) {
+	t.Parallel()
+
+	logger := testutils.NewTestLogger()
+	reporter := reporting.NewMemoryReporter()
+
+	var metrics []middlewares.RequestMetrics
+	recorder := middlewares.MetricsRecorderFunc(func(m middlewares.RequestMetrics) {
+		metrics = append(metrics, m)
+	})
+
+	handler := middlewares.DefaultChain(logger, reporter, recorder).ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/test", nil)
+	req.Header.Set(middlewares.RequestIDHeader, "abc")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("want status code %d. got %d", http.StatusInternalServerError, rr.Code)
+	}
+
+	if rr.Header().Get(middlewares.RequestIDHeader) != "abc" {
+		t.Errorf("want request id %q. got %q", "abc", rr.Header().Get(middlewares.RequestIDHeader))
+	}
+
+	if len(reporter.Events()) != 1 {
+		t.Errorf("want 1 reported panic. got %d", len(reporter.Events()))
+	}
+
+	if logger.Context["request_id"] != "abc" {
+		t.Errorf("want logged request id %q. got %v", "abc", logger.Context["request_id"])
+	}
+
+	// metrics wraps recovery, so the panic is recorded as a 500
+	if len(metrics) != 1 || metrics[0].Status != http.StatusInternalServerError {
+		t.Errorf("want 1 metric with status code %d. got %v", http.StatusInternalServerError, metrics)
+	}
+}
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/concurrency"
+)
+
+// ConcurrencyLimit returns a Middleware that limits the number of requests in flight with l.
+// Shed requests are rejected with a 503 concurrency.Error through httputils.ServeRequestError.
+func ConcurrencyLimit(l *concurrency.Limiter) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t, err := l.Admit(w, r)
+			if err != nil {
+				httputils.ServeRequestError(w, r, err)
+				return
+			}
+			defer t.Release()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/concurrency"
+	"github.com/viaduct-ai/vgo/httputils/middlewares"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+
+	l := concurrency.NewLimiter(concurrency.Config{
+		Limit: concurrency.FixedLimit(1),
+	})
+
+	var inner *httptest.ResponseRecorder
+
+	handler := middlewares.ConcurrencyLimit(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a request made while this one is in flight is shed
+		inner = httptest.NewRecorder()
+		middlewares.ConcurrencyLimit(l)(http.HandlerFunc(dummyHandler)).ServeHTTP(inner, r)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(httputils.Accept, httputils.ContentTypeProblemJSON)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("want status code %d. got %d", http.StatusOK, rr.Code)
+	}
+
+	if inner.Code != http.StatusServiceUnavailable {
+		t.Errorf("want status code %d. got %d", http.StatusServiceUnavailable, inner.Code)
+	}
+
+	if got := inner.Header().Get(httputils.ContentType); got != httputils.ContentTypeProblemJSON {
+		t.Errorf("want Content-Type %s. got %s", httputils.ContentTypeProblemJSON, got)
+	}
+
+	if got := l.Stats().InFlight; got != 0 {
+		t.Errorf("want no requests in flight. got %d", got)
+	}
+}
@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/viaduct-ai/vgo/httputils/cors"
+)
+
+// CORS returns a Middleware that sets the CORS headers configured by cfg and answers preflight requests
+func CORS(cfg *cors.Config) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.Handle(w, r) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils/cors"
+	"github.com/viaduct-ai/vgo/httputils/middlewares"
+)
+
+func TestCORS(t *testing.T) {
+	t.Parallel()
+
+	called := false
+	handler := middlewares.CORS(&cors.Config{
+		AllowedOrigins: []string{"https://app.viaduct.ai"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://app.viaduct.ai")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("want status code %d. got %d", http.StatusNoContent, rr.Code)
+	}
+
+	if called {
+		t.Errorf("want preflight request not to reach the handler")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Origin", "https://app.viaduct.ai")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if !called {
+		t.Errorf("want request to reach the handler")
+	}
+
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://app.viaduct.ai" {
+		t.Errorf("want origin https://app.viaduct.ai. got %s", got)
+	}
+}
@@ -0,0 +1,17 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/viaduct-ai/vgo/httputils/idempotency"
+)
+
+// Idempotency returns a Middleware that honors the Idempotency-Key header of requests as configured by cfg,
+// replaying the stored response of retried requests
+func Idempotency(cfg *idempotency.Config) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cfg.Serve(w, r, next)
+		})
+	}
+}
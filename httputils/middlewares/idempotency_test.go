@@ -0,0 +1,39 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils/idempotency"
+	"github.com/viaduct-ai/vgo/httputils/middlewares"
+)
+
+func TestIdempotency(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	handler := middlewares.Idempotency(&idempotency.Config{
+		// requests of unknown callers are not deduplicated
+		Caller: func(r *http.Request) string { return "alice" },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusCreated {
+			t.Errorf("want status code %d. got %d", http.StatusCreated, rr.Code)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("want handler to be called once. got %d", calls)
+	}
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+)
+
+// RequestMetrics describes a completed request
+type RequestMetrics struct {
+	Method string
+	// Path is the request path. Recorders should normalize it to avoid high cardinality labels.
+	Path     string
+	Status   int
+	Size     int
+	Duration time.Duration
+}
+
+// MetricsRecorder records request metrics to a metrics backend such as Prometheus or StatsD
+type MetricsRecorder interface {
+	RecordRequest(m RequestMetrics)
+}
+
+// MetricsRecorderFunc adapts a function to a MetricsRecorder
+type MetricsRecorderFunc func(m RequestMetrics)
+
+// RecordRequest calls f(m)
+func (f MetricsRecorderFunc) RecordRequest(m RequestMetrics) {
+	f(m)
+}
+
+// Metrics returns a Middleware that records the method, path, status, size and duration of every request with rec
+func Metrics(rec MetricsRecorder) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := newResponseWriter(w)
+
+			next.ServeHTTP(rw, r)
+
+			rec.RecordRequest(RequestMetrics{
+				Method:   r.Method,
+				Path:     r.URL.Path,
+				Status:   rw.status,
+				Size:     rw.size,
+				Duration: time.Since(start),
+			})
+		})
+	}
+}
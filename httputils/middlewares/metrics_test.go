@@ -0,0 +1,37 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils/middlewares"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	var got middlewares.RequestMetrics
+	recorder := middlewares.MetricsRecorderFunc(func(m middlewares.RequestMetrics) {
+		got = m
+	})
+
+	handler := middlewares.Metrics(recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/test", nil))
+
+	if got.Method != http.MethodPost || got.Path != "/v1/test" {
+		t.Errorf("want %s /v1/test. got %s %s", http.MethodPost, got.Method, got.Path)
+	}
+
+	if got.Status != http.StatusCreated || got.Size != len("created") {
+		t.Errorf("want status %d and size %d. got %d and %d", http.StatusCreated, len("created"), got.Status, got.Size)
+	}
+
+	if got.Duration <= 0 {
+		t.Errorf("want a positive duration. got %v", got.Duration)
+	}
+}
@@ -1,12 +1,10 @@
 package middlewares
 
 import (
-	"bytes"
-	"encoding/json"
-	"io/ioutil"
 	"net/http"
+	"time"
 
-	"github.com/viaduct-ai/vgo/jwtutils"
+	"github.com/viaduct-ai/vgo/httputils/accesslog"
 	"github.com/viaduct-ai/vgo/log"
 )
 
@@ -23,48 +21,38 @@ var (
 
 // TODO: Decide about including gorilla proxy headers
 
-// LoggingMiddleware logs all incoming requests and response headers using the internal logger
-func LoggingMiddleware(l log.Logger, next http.Handler) http.Handler {
-	logRequest := func(w http.ResponseWriter, r *http.Request) {
+// LoggingMiddleware logs all incoming requests and response headers using the internal logger.
+// Fields are named by the accesslog.Schema in opts, which defaults to the legacy vgo keys.
+// A response entry with the status, size and latency is logged if enabled with accesslog.WithResponse.
+func LoggingMiddleware(l log.Logger, next http.Handler, opts ...accesslog.Option) http.Handler {
+	o := accesslog.NewOptions(opts...)
 
-		// try parse claims from request, ignore errors
-		claims, _ := jwtutils.ParseUnverifiedTokenClaimsFromRequest(r)
-
-		// read, close, re-create
-		body, err := ioutil.ReadAll(r.Body)
-		r.Body.Close()
-		r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
-
-		rData := map[string]interface{}{}
+	logHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.Skip(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		if err == nil {
-			json.Unmarshal(body, &rData)
+		req := o.NewRequest(r)
+		req.RequestID = RequestIDFromContext(r.Context())
+		log.InfoFields(l, "request", o.Schema.RequestFields(req)...)
 
-			// Omit password from logs
-			// Need to find a more generic approach for any sensitive fields
-			delete(rData, "password")
+		if !o.LogResponse {
+			next.ServeHTTP(w, r)
+			return
 		}
 
-		l.With(
-			"method", r.Method,
-			"host", r.Host,
-			"url", r.URL.String(),
-			"endpoint", r.URL.Path,
-			"scheme", r.URL.Scheme,
-			"ip", r.RemoteAddr,
-			"headers", r.Header,
-			"query", r.URL.Query(),
-			"body", rData,
-			"auth", claims,
-		).Info("request")
-	}
+		start := time.Now()
+		rw := newResponseWriter(w)
 
-	logHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		logRequest(w, r)
-		next.ServeHTTP(w, r)
-		// TODO: Log Response Code and Metrics
-		// This is harder than expected, consider using https://github.com/felixge/httpsnoop
-		// logResponse(w, r)
+		next.ServeHTTP(rw, r)
+
+		resp := &accesslog.Response{
+			Status:  rw.status,
+			Size:    rw.size,
+			Latency: time.Since(start),
+		}
+		log.InfoFields(l, "response", o.Schema.ResponseFields(req, resp)...)
 	})
 
 	return logHandler
@@ -8,10 +8,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/viaduct-ai/vgo/httputils/accesslog"
 	"github.com/viaduct-ai/vgo/httputils/middlewares"
+	"github.com/viaduct-ai/vgo/log"
 	"github.com/viaduct-ai/vgo/testutils"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/net/context"
 )
 
@@ -57,8 +61,8 @@ func TestLoggingMiddleware(t *testing.T) {
 				"endpoint": baseReq.URL.Path,
 				"scheme":   baseReq.URL.Scheme,
 				"ip":       baseReq.RemoteAddr,
-				"headers":  baseReq.Header,
-				"query":    baseReq.URL.Query(),
+				"headers":  loggedStrings(baseReq.Header),
+				"query":    loggedStrings(baseReq.URL.Query()),
 				"body":     map[string]interface{}{},
 				"auth":     map[string]interface{}{},
 			},
@@ -74,8 +78,8 @@ func TestLoggingMiddleware(t *testing.T) {
 				"endpoint": authReq.URL.Path,
 				"scheme":   authReq.URL.Scheme,
 				"ip":       authReq.RemoteAddr,
-				"headers":  authReq.Header,
-				"query":    authReq.URL.Query(),
+				"headers":  loggedStrings(authReq.Header),
+				"query":    loggedStrings(authReq.URL.Query()),
 				"body":     map[string]interface{}{},
 				"auth": map[string]interface{}{
 					"iat":  json.Number("1516239022"),
@@ -95,8 +99,8 @@ func TestLoggingMiddleware(t *testing.T) {
 				"endpoint": loginReq.URL.Path,
 				"scheme":   loginReq.URL.Scheme,
 				"ip":       loginReq.RemoteAddr,
-				"headers":  loginReq.Header,
-				"query":    loginReq.URL.Query(),
+				"headers":  loggedStrings(loginReq.Header),
+				"query":    loggedStrings(loginReq.URL.Query()),
 				"body": map[string]interface{}{
 					"username": "test",
 				},
@@ -147,3 +151,89 @@ func TestLoggingMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestLoggingMiddlewareResponse(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+
+	logger := testutils.NewTestLogger()
+	middleware := middlewares.LoggingMiddleware(logger, handler,
+		accesslog.WithSchema(accesslog.OTelSchema),
+		accesslog.WithResponse(true),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "https://api.test.com/v1/token", nil)
+	rr := httptest.NewRecorder()
+
+	middleware.ServeHTTP(rr, req)
+
+	wantLogs := []interface{}{"request", "response"}
+	if !reflect.DeepEqual(wantLogs, logger.InfoLogs) {
+		t.Errorf("want info logs %v. got %v", wantLogs, logger.InfoLogs)
+	}
+
+	if logger.Context["http.response.status_code"] != int64(http.StatusCreated) {
+		t.Errorf("want status code %d. got %v", http.StatusCreated, logger.Context["http.response.status_code"])
+	}
+
+	if logger.Context["http.response.body.size"] != int64(len("created")) {
+		t.Errorf("want body size %d. got %v", len("created"), logger.Context["http.response.body.size"])
+	}
+
+	if logger.Context["url.path"] != "/v1/token" {
+		t.Errorf("want path %q. got %v", "/v1/token", logger.Context["url.path"])
+	}
+}
+
+// plainLogger hides the FieldLogger methods of the Logger it wraps
+type plainLogger struct {
+	log.Logger
+}
+
+func BenchmarkLoggingMiddleware(b *testing.B) {
+	sink, err := log.WriterSink(ioutil.Discard, log.JSONEncoding, zapcore.InfoLevel)
+	if err != nil {
+		b.Fatalf("error creating sink: %v", err)
+	}
+
+	loggers := []struct {
+		name   string
+		logger log.Logger
+	}{
+		{name: "FieldLogger", logger: log.NewTeeLogger(sink)},
+		{name: "Logger", logger: plainLogger{log.NewTeeLogger(sink)}},
+	}
+
+	for _, l := range loggers {
+		handler := middlewares.LoggingMiddleware(l.logger, http.HandlerFunc(dummyHandler))
+
+		b.Run(l.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest(http.MethodPost, "https://api.test.com/v1/users?q=test", strings.NewReader(`{"username": "test"}`))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("User-Agent", "test")
+
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		})
+	}
+}
+
+// loggedStrings is the logged form of multi-valued maps such as http.Header and url.Values
+func loggedStrings(m map[string][]string) map[string]interface{} {
+	logged := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		values := make([]interface{}, len(v))
+		for i, s := range v {
+			values[i] = s
+		}
+		logged[k] = values
+	}
+
+	return logged
+}
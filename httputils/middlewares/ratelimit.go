@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/ratelimit"
+)
+
+// RateLimit returns a Middleware that limits the rate of requests per caller as configured by cfg.
+// Limited requests are rejected with a 429 ratelimit.Error through httputils.ServeRequestError.
+func RateLimit(cfg *ratelimit.Config) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := cfg.Check(w, r); err != nil {
+				httputils.ServeRequestError(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/middlewares"
+	"github.com/viaduct-ai/vgo/httputils/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := ratelimit.NewTokenBucket(ratelimit.PerMinute(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := middlewares.RateLimit(&ratelimit.Config{
+		Key:     ratelimit.ByIP,
+		Limiter: limiter,
+	})(http.HandlerFunc(dummyHandler))
+
+	wantStatus := []int{http.StatusOK, http.StatusTooManyRequests}
+	for _, want := range wantStatus {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rr.Code != want {
+			t.Errorf("want status code %d. got %d", want, rr.Code)
+		}
+	}
+
+	// the error is served as requested
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(httputils.Accept, httputils.ContentTypeProblemJSON)
+	handler.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get(httputils.ContentType); got != httputils.ContentTypeProblemJSON {
+		t.Errorf("want Content-Type %s. got %s", httputils.ContentTypeProblemJSON, got)
+	}
+}
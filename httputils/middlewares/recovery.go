@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/log"
+	"github.com/viaduct-ai/vgo/reporting"
+)
+
+// RecoveryMiddleware recovers panics in next, logs them, reports them with request metadata to r
+// and serves an internal error response, unless next has already started writing the response.
+// Panics are not reported if r is nil. If r is set, the panic is logged with the reporting.Reported field,
+// so l does not report it a second time if it is a reporting Logger.
+// http.ErrAbortHandler is re-panicked so the server can abort the response as intended.
+func RecoveryMiddleware(l log.Logger, r reporting.Reporter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rw := newResponseWriter(w)
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			e := reporting.NewPanicEvent(rec).WithRequest(req)
+
+			fields := []log.Field{
+				log.String("method", req.Method),
+				log.String("url", req.URL.String()),
+				log.String("fingerprint", e.Fingerprint),
+			}
+
+			if r != nil {
+				r.Report(e)
+				fields = append(fields, reporting.Reported())
+			}
+
+			log.ErrorFields(l, e.Message, fields...)
+
+			// the status cannot change once the response has started
+			if !rw.wroteHeader {
+				httputils.ServeError(rw, nil)
+			}
+		}()
+
+		next.ServeHTTP(rw, req)
+	})
+}
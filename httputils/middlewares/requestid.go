@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// maxRequestIDLength bounds the length of request IDs accepted from clients
+const maxRequestIDLength = 128
+
+var (
+	// RequestIDHeader is the header request IDs are read from and written to
+	RequestIDHeader = http.CanonicalHeaderKey("X-Request-Id")
+)
+
+type requestIDContextKey struct{}
+
+// RequestID returns a Middleware that propagates the X-Request-Id header of the request, or generates one.
+// The ID is stored in the request context and set on the response.
+// Envoy sets X-Request-Id on every request it proxies, so IDs are usually propagated.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if id == "" || len(id) > maxRequestIDLength {
+				id = newRequestID()
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := context.WithValue(r.Context(), requestIDContextKey{}, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIDFromContext returns the request ID set by the RequestID middleware, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand only fails if the OS is unable to provide randomness
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
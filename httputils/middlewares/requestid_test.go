@@ -0,0 +1,62 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils/middlewares"
+)
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		requestID string
+		wantID    string
+	}{
+		{
+			name:      "Propagated",
+			requestID: "abc",
+			wantID:    "abc",
+		},
+		{
+			name: "Generated",
+		},
+		{
+			name:      "Too Long",
+			requestID: strings.Repeat("a", 129),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotID string
+			handler := middlewares.RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotID = middlewares.RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.requestID != "" {
+				req.Header.Set(middlewares.RequestIDHeader, tt.requestID)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if tt.wantID != "" && gotID != tt.wantID {
+				t.Errorf("want request id %q. got %q", tt.wantID, gotID)
+			}
+
+			if tt.wantID == "" && (len(gotID) != 32 || gotID == tt.requestID) {
+				t.Errorf("want a generated request id. got %q", gotID)
+			}
+
+			if rr.Header().Get(middlewares.RequestIDHeader) != gotID {
+				t.Errorf("want response header %q. got %q", gotID, rr.Header().Get(middlewares.RequestIDHeader))
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/security"
+)
+
+// SecurityHeaders returns a Middleware that sets the security headers configured by cfg,
+// such as security.DefaultConfig()
+func SecurityHeaders(cfg *security.Config) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, err := cfg.Apply(w, r)
+			if err != nil {
+				httputils.ServeRequestError(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
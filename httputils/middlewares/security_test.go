@@ -0,0 +1,35 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils/csp"
+	"github.com/viaduct-ai/vgo/httputils/middlewares"
+	"github.com/viaduct-ai/vgo/httputils/security"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	t.Parallel()
+
+	cfg := security.DefaultConfig()
+	cfg.CSP.Nonce(csp.ScriptSrc)
+
+	var nonce string
+	handler := middlewares.SecurityHeaders(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = csp.NonceFromContext(r.Context())
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if nonce == "" {
+		t.Errorf("want nonce in request context")
+	}
+
+	want := "default-src 'none'; frame-ancestors 'none'; script-src 'nonce-" + nonce + "'"
+	if got := rr.Header().Get("Content-Security-Policy"); got != want {
+		t.Errorf("want %q. got %q", want, got)
+	}
+}
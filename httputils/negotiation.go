@@ -0,0 +1,386 @@
+package httputils
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/gddo/httputil/header"
+	"github.com/golang/protobuf/proto"
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	// ContentTypeXML is a constant for XML Header Type
+	ContentTypeXML = "application/xml"
+	// ContentTypeMsgpack is a constant for MessagePack Header Type
+	ContentTypeMsgpack = "application/msgpack"
+	// ContentTypeProtobuf is a constant for Protocol Buffers Header Type
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+var (
+	// Accept is a constant for the Accept header
+	Accept = http.CanonicalHeaderKey("Accept")
+
+	vary = http.CanonicalHeaderKey("Vary")
+)
+
+// Codec encodes response bodies and decodes request bodies of a media type
+type Codec interface {
+	// ContentTypes are the media types of the Codec. The first one is used for responses.
+	ContentTypes() []string
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// A Codec that can only encode some values, such as Protocol Buffers messages, implements Supports
+// so it is not negotiated for other values
+type supporter interface {
+	Supports(v interface{}) bool
+}
+
+type negotiationError struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (ne *negotiationError) Error() string {
+	return ne.msg
+}
+
+func (ne *negotiationError) Status() int {
+	return ne.status
+}
+
+func (ne *negotiationError) Message() string {
+	return ne.msg
+}
+
+func (ne *negotiationError) Code() string {
+	return ne.code
+}
+
+var (
+	codecsMu sync.RWMutex
+	// codecs is replaced, never modified, so the slices returned by registeredCodecs can be used without the lock
+	codecs = []Codec{JSONCodec, XMLCodec, MsgpackCodec, ProtobufCodec}
+)
+
+// RegisterCodec makes c available to Serve and DecodeBody, replacing any Codec of the same first content type.
+// Codecs are preferred in the order they are registered when the Accept header does not prefer one,
+// JSONCodec being first.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	updated := make([]Codec, len(codecs), len(codecs)+1)
+	copy(updated, codecs)
+	defer func() { codecs = updated }()
+
+	for i, registered := range updated {
+		if registered.ContentTypes()[0] == c.ContentTypes()[0] {
+			updated[i] = c
+			return
+		}
+	}
+
+	updated = append(updated, c)
+}
+
+func registeredCodecs() []Codec {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	return codecs
+}
+
+// Serve serves body with the status, encoded by the registered Codec best matching the Accept header of r.
+// Without an Accept header, body is served as JSON. If no Codec is acceptable, a 406 APIError is served
+// as JSON instead. The Vary header is set, as the response depends on the Accept header.
+//
+// JSON is pretty-printed if r has a "pretty" query parameter or pretty-printing is enabled with SetPrettyJSON.
+func Serve(w http.ResponseWriter, r *http.Request, status int, body interface{}) {
+	w.Header().Add(vary, Accept)
+
+	c, err := negotiate(r, body)
+	if err != nil {
+		ServeError(w, err)
+		return
+	}
+
+	if _, ok := c.(jsonCodec); ok {
+		serveJSON(w, status, body, prettyRequest(r))
+		return
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer putBuffer(buf)
+
+	if err := c.Encode(buf, body); err != nil {
+		ServeError(w, err)
+		return
+	}
+
+	w.Header().Set(ContentType, c.ContentTypes()[0])
+	w.Header().Set(contentLength, strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// negotiate picks the Codec with the highest quality in the Accept header of r, in registration order on ties
+func negotiate(r *http.Request, body interface{}) (Codec, error) {
+	specs := header.ParseAccept(r.Header, Accept)
+
+	var best Codec
+	bestQ := 0.0
+
+	for _, c := range registeredCodecs() {
+		if s, ok := c.(supporter); ok && !s.Supports(body) {
+			continue
+		}
+
+		if len(specs) == 0 {
+			return c, nil
+		}
+
+		if q := quality(specs, c.ContentTypes()); q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+
+	if best == nil {
+		var types []string
+		for _, c := range registeredCodecs() {
+			types = append(types, c.ContentTypes()[0])
+		}
+
+		msg := fmt.Sprintf("%s header must accept one of %s", Accept, strings.Join(types, ", "))
+		return nil, &negotiationError{status: http.StatusNotAcceptable, code: "not_acceptable", msg: msg}
+	}
+
+	return best, nil
+}
+
+// quality returns the quality of the most specific Accept media range matching any of contentTypes
+func quality(specs []header.AcceptSpec, contentTypes []string) float64 {
+	q, specificity := 0.0, -1
+
+	for _, spec := range specs {
+		for _, ct := range contentTypes {
+			s := -1
+			switch {
+			case strings.EqualFold(spec.Value, ct):
+				s = 2
+			case strings.HasSuffix(spec.Value, "/*") && strings.HasPrefix(ct, strings.TrimSuffix(spec.Value, "*")):
+				s = 1
+			case spec.Value == "*/*":
+				s = 0
+			}
+
+			if s < 0 {
+				continue
+			}
+
+			if s > specificity || (s == specificity && spec.Q > q) {
+				q, specificity = spec.Q, s
+			}
+		}
+	}
+
+	return q
+}
+
+// DecodeBody decodes the request body into dst with the registered Codec of its Content-Type header.
+// JSON bodies, and bodies without a Content-Type, are decoded strictly by DecodeJSONBody.
+// Other bodies are limited to 1MB as well. An unknown Content-Type is a 415 APIError.
+func DecodeBody(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	value, _ := header.ParseValueAndParams(r.Header, ContentType)
+	if value == "" || value == ContentTypeJSON {
+		return DecodeJSONBody(w, r, dst)
+	}
+
+	var c Codec
+	for _, registered := range registeredCodecs() {
+		for _, ct := range registered.ContentTypes() {
+			if value == ct {
+				c = registered
+			}
+		}
+	}
+
+	if c == nil {
+		msg := fmt.Sprintf("%s header %s is not supported", ContentType, value)
+		return &malformedRequest{status: http.StatusUnsupportedMediaType, msg: msg}
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, oneMB))
+	if err != nil {
+		if isBodyTooLarge(err) {
+			return &malformedRequest{status: http.StatusRequestEntityTooLarge, msg: msgRequestTooLarge}
+		}
+		return err
+	}
+
+	if len(body) == 0 {
+		msg := "request body must not be empty"
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	if err := c.Decode(bytes.NewReader(body), dst); err != nil {
+		msg := fmt.Sprintf("request body contains badly-formed %s: %v", value, err)
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	return nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentTypes() []string {
+	return []string{ContentTypeJSON}
+}
+
+func (jsonCodec) Encode(w io.Writer, v interface{}) error {
+	buf, err := encodeJSON(v, prettyJSON())
+	if err != nil {
+		return err
+	}
+	defer putBuffer(buf)
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func (jsonCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentTypes() []string {
+	return []string{ContentTypeXML, "text/xml"}
+}
+
+// Supports values encoding/xml can marshal, so maps are served as JSON, or refused with a 406
+func (xmlCodec) Supports(v interface{}) bool {
+	return v == nil || xmlSupports(reflect.TypeOf(v))
+}
+
+// xmlSupports returns false for types encoding/xml can't marshal, such as maps and slices of maps.
+// Unsupported types nested in structs or interfaces are not detected.
+func xmlSupports(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Map, reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Slice, reflect.Array:
+		return xmlSupports(t.Elem())
+	default:
+		return true
+	}
+}
+
+func (xmlCodec) Encode(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (xmlCodec) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+type msgpackCodec struct {
+	handle *codec.MsgpackHandle
+}
+
+func newMsgpackCodec() msgpackCodec {
+	h := &codec.MsgpackHandle{}
+	// decode maps as JSON would, for interface{} values
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	h.RawToString = true
+	h.WriteExt = true
+
+	return msgpackCodec{handle: h}
+}
+
+func (msgpackCodec) ContentTypes() []string {
+	return []string{ContentTypeMsgpack, "application/x-msgpack"}
+}
+
+func (c msgpackCodec) Encode(w io.Writer, v interface{}) error {
+	return codec.NewEncoder(w, c.handle).Encode(v)
+}
+
+func (c msgpackCodec) Decode(r io.Reader, v interface{}) error {
+	return codec.NewDecoder(r, c.handle).Decode(v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentTypes() []string {
+	return []string{ContentTypeProtobuf, "application/protobuf"}
+}
+
+// Supports only proto.Message values
+func (protobufCodec) Supports(v interface{}) bool {
+	_, ok := v.(proto.Message)
+	return ok
+}
+
+func (protobufCodec) Encode(w io.Writer, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.New("protobuf: value is not a proto.Message")
+	}
+
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+func (protobufCodec) Decode(r io.Reader, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errors.New("protobuf: value is not a proto.Message")
+	}
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return proto.Unmarshal(b, m)
+}
+
+var (
+	// JSONCodec encodes and decodes application/json like ServeJSON
+	JSONCodec Codec = jsonCodec{}
+	// XMLCodec encodes and decodes application/xml and text/xml with encoding/xml
+	XMLCodec Codec = xmlCodec{}
+	// MsgpackCodec encodes and decodes application/msgpack, using the codec or json struct tags
+	MsgpackCodec Codec = newMsgpackCodec()
+	// ProtobufCodec encodes and decodes application/x-protobuf. It only supports proto.Message values.
+	ProtobufCodec Codec = protobufCodec{}
+)
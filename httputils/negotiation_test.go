@@ -0,0 +1,330 @@
+package httputils_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/ugorji/go/codec"
+
+	"github.com/viaduct-ai/vgo/httputils"
+)
+
+type negotiated struct {
+	Name  string `json:"name" xml:"name"`
+	Count int    `json:"count" xml:"count"`
+}
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+
+	body := negotiated{Name: "thing", Count: 2}
+
+	tests := []struct {
+		name            string
+		accept          string
+		body            interface{}
+		wantStatus      int
+		wantContentType string
+	}{
+		{
+			name:            "No Accept",
+			body:            body,
+			wantStatus:      http.StatusOK,
+			wantContentType: httputils.ContentTypeJSON,
+		},
+		{
+			name:            "Any",
+			accept:          "*/*",
+			body:            body,
+			wantStatus:      http.StatusOK,
+			wantContentType: httputils.ContentTypeJSON,
+		},
+		{
+			name:            "XML",
+			accept:          "text/html, application/xml;q=0.9, */*;q=0.8",
+			body:            body,
+			wantStatus:      http.StatusOK,
+			wantContentType: httputils.ContentTypeXML,
+		},
+		{
+			name:            "Msgpack",
+			accept:          "application/json;q=0.5, application/x-msgpack",
+			body:            body,
+			wantStatus:      http.StatusOK,
+			wantContentType: httputils.ContentTypeMsgpack,
+		},
+		{
+			name:            "Protobuf",
+			accept:          "application/x-protobuf",
+			body:            &wrappers.StringValue{Value: "thing"},
+			wantStatus:      http.StatusOK,
+			wantContentType: httputils.ContentTypeProtobuf,
+		},
+		{
+			name:            "Protobuf Unsupported",
+			accept:          "application/x-protobuf, application/json;q=0.1",
+			body:            body,
+			wantStatus:      http.StatusOK,
+			wantContentType: httputils.ContentTypeJSON,
+		},
+		{
+			name:            "Excluded",
+			accept:          "application/json;q=0, application/*",
+			body:            body,
+			wantStatus:      http.StatusOK,
+			wantContentType: httputils.ContentTypeXML,
+		},
+		{
+			name:            "XML Unsupported",
+			accept:          "application/xml, application/json;q=0.5",
+			body:            map[string]int{"count": 2},
+			wantStatus:      http.StatusOK,
+			wantContentType: httputils.ContentTypeJSON,
+		},
+		{
+			name:            "XML Unsupported Not Acceptable",
+			accept:          "application/xml",
+			body:            []map[string]int{{"count": 2}},
+			wantStatus:      http.StatusNotAcceptable,
+			wantContentType: httputils.ContentTypeJSON,
+		},
+		{
+			name:            "Not Acceptable",
+			accept:          "text/html",
+			body:            body,
+			wantStatus:      http.StatusNotAcceptable,
+			wantContentType: httputils.ContentTypeJSON,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			rr := httptest.NewRecorder()
+			httputils.Serve(rr, req, http.StatusOK, tt.body)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("want status code %d. got %d", tt.wantStatus, rr.Code)
+			}
+
+			if got := rr.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("want content type %s. got %s", tt.wantContentType, got)
+			}
+
+			if got := rr.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("want Vary Accept. got %s", got)
+			}
+		})
+	}
+}
+
+func TestServeEncoding(t *testing.T) {
+	t.Parallel()
+
+	body := negotiated{Name: "thing", Count: 2}
+
+	tests := []struct {
+		name   string
+		accept string
+		decode func(b []byte, v interface{}) error
+	}{
+		{
+			name:   "JSON",
+			accept: httputils.ContentTypeJSON,
+			decode: json.Unmarshal,
+		},
+		{
+			name:   "XML",
+			accept: httputils.ContentTypeXML,
+			decode: func(b []byte, v interface{}) error {
+				return httputils.XMLCodec.Decode(bytes.NewReader(b), v)
+			},
+		},
+		{
+			name:   "Msgpack",
+			accept: httputils.ContentTypeMsgpack,
+			decode: func(b []byte, v interface{}) error {
+				return codec.NewDecoderBytes(b, &codec.MsgpackHandle{}).Decode(v)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", tt.accept)
+
+			rr := httptest.NewRecorder()
+			httputils.Serve(rr, req, http.StatusOK, body)
+
+			var got negotiated
+			if err := tt.decode(rr.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, body) {
+				t.Errorf("want %+v. got %+v", body, got)
+			}
+		})
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	t.Parallel()
+
+	want := negotiated{Name: "thing", Count: 2}
+
+	var msgpack []byte
+	codec.NewEncoderBytes(&msgpack, &codec.MsgpackHandle{}).Encode(want)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		wantStatus  int
+	}{
+		{
+			name:        "JSON",
+			contentType: httputils.ContentTypeJSON,
+			body:        []byte(`{"name": "thing", "count": 2}`),
+		},
+		{
+			name:        "JSON Unknown Field",
+			contentType: httputils.ContentTypeJSON,
+			body:        []byte(`{"name": "thing", "count": 2, "color": "red"}`),
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "XML",
+			contentType: "text/xml; charset=utf-8",
+			body:        []byte(`<negotiated><name>thing</name><count>2</count></negotiated>`),
+		},
+		{
+			name:        "Msgpack",
+			contentType: httputils.ContentTypeMsgpack,
+			body:        msgpack,
+		},
+		{
+			name:        "Malformed",
+			contentType: httputils.ContentTypeXML,
+			body:        []byte(`<negotiated>`),
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "Unsupported",
+			contentType: "text/csv",
+			body:        []byte("thing,2"),
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			var got negotiated
+			err := httputils.DecodeBody(httptest.NewRecorder(), req, &got)
+
+			if tt.wantStatus != 0 {
+				apiError, ok := err.(httputils.APIError)
+				if !ok || apiError.Status() != tt.wantStatus {
+					t.Errorf("want APIError with status code %d. got %v", tt.wantStatus, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error. got %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("want %+v. got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeBodyProtobuf(t *testing.T) {
+	t.Parallel()
+
+	b, _ := proto.Marshal(&wrappers.StringValue{Value: "thing"})
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/protobuf")
+
+	var got wrappers.StringValue
+	if err := httputils.DecodeBody(httptest.NewRecorder(), req, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Value != "thing" {
+		t.Errorf("want thing. got %s", got.Value)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x"))
+	req.Header.Set("Content-Type", httputils.ContentTypeProtobuf)
+
+	if err := httputils.DecodeBody(httptest.NewRecorder(), req, &negotiated{}); err == nil {
+		t.Errorf("want error decoding protobuf into a non proto.Message")
+	}
+}
+
+type testCodec struct{}
+
+func (testCodec) ContentTypes() []string {
+	return []string{"application/x-test"}
+}
+
+func (testCodec) Encode(w io.Writer, v interface{}) error {
+	_, err := io.WriteString(w, "test")
+	return err
+}
+
+func (testCodec) Decode(r io.Reader, v interface{}) error {
+	return nil
+}
+
+func TestRegisterCodecConcurrently(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			httputils.RegisterCodec(testCodec{})
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", "application/x-test, application/json;q=0.5")
+			httputils.Serve(httptest.NewRecorder(), req, http.StatusOK, "thing")
+		}
+	}()
+
+	wg.Wait()
+}
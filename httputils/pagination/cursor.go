@@ -0,0 +1,162 @@
+package pagination
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// macSize is the size of the truncated HMAC-SHA256 signature of cursors
+const macSize = 16
+
+// errNoSecret is returned when cursors are used without a Config.Secret
+var errNoSecret = errors.New("pagination: Config.Secret is required for cursors")
+
+// Cursor is the position of a page
+type Cursor struct {
+	// Key is the position of the page, such as the sort key and ID of the last item of the previous page
+	Key json.RawMessage `json:"k"`
+	// Backward is set for cursors to the previous page, whose items are before Key
+	Backward bool `json:"b,omitempty"`
+	// Expires is the unix time the cursor expires at, or 0
+	Expires int64 `json:"e,omitempty"`
+}
+
+// DecodeKey decodes the Key of the cursor into dst
+func (cur *Cursor) DecodeKey(dst interface{}) error {
+	if err := json.Unmarshal(cur.Key, dst); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// NextCursor returns a cursor token to the page after key
+func (c *Config) NextCursor(key interface{}) (string, error) {
+	return c.Encode(key, false)
+}
+
+// PrevCursor returns a cursor token to the page before key
+func (c *Config) PrevCursor(key interface{}) (string, error) {
+	return c.Encode(key, true)
+}
+
+// Encode returns an opaque cursor token to the page after key, or before key if backward is set.
+// key is encoded as JSON.
+func (c *Config) Encode(key interface{}, backward bool) (string, error) {
+	if len(c.Secret) == 0 {
+		return "", errNoSecret
+	}
+
+	k, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+
+	cur := Cursor{Key: k, Backward: backward}
+	if c.TTL > 0 {
+		cur.Expires = c.clock().Add(c.TTL).Unix()
+	}
+
+	payload, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+
+	if len(c.EncryptionKey) > 0 {
+		if payload, err = c.encrypt(payload); err != nil {
+			return "", err
+		}
+	}
+
+	token := append(payload, c.sign(payload)...)
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// Decode verifies and decodes a cursor token. It returns ErrInvalidCursor or ErrExpiredCursor,
+// 400 APIErrors, for tokens that were not issued by c or have expired. An invalid configuration,
+// such as an EncryptionKey of the wrong size, is an error regardless of the token.
+func (c *Config) Decode(token string) (*Cursor, error) {
+	if len(c.Secret) == 0 {
+		return nil, errNoSecret
+	}
+
+	var gcm cipher.AEAD
+	if len(c.EncryptionKey) > 0 {
+		var err error
+		if gcm, err = c.gcm(); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) < macSize {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, mac := b[:len(b)-macSize], b[len(b)-macSize:]
+	if !hmac.Equal(mac, c.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	if gcm != nil {
+		if payload, err = decrypt(gcm, payload); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	var cur Cursor
+	if err := json.Unmarshal(payload, &cur); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if cur.Expires > 0 && c.clock().Unix() > cur.Expires {
+		return nil, ErrExpiredCursor
+	}
+
+	return &cur, nil
+}
+
+func (c *Config) sign(payload []byte) []byte {
+	h := hmac.New(sha256.New, c.Secret)
+	h.Write(payload)
+	return h.Sum(nil)[:macSize]
+}
+
+func (c *Config) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(c.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("pagination: Config.EncryptionKey is invalid: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt returns the nonce followed by the sealed payload
+func (c *Config) encrypt(payload []byte) ([]byte, error) {
+	gcm, err := c.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, payload, nil), nil
+}
+
+func decrypt(gcm cipher.AEAD, b []byte) ([]byte, error) {
+	if len(b) < gcm.NonceSize() {
+		return nil, errors.New("pagination: cursor is too short")
+	}
+
+	nonce, sealed := b[:gcm.NonceSize()], b[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
@@ -0,0 +1,190 @@
+package pagination_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/pagination"
+)
+
+type position struct {
+	CreatedAt string `json:"created_at"`
+	ID        int    `json:"id"`
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *pagination.Config
+	}{
+		{
+			name: "signed",
+			cfg:  &pagination.Config{Secret: []byte("secret")},
+		},
+		{
+			name: "encrypted",
+			cfg:  &pagination.Config{Secret: []byte("secret"), EncryptionKey: []byte("0123456789abcdef")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := position{CreatedAt: "2021-01-02T03:04:05Z", ID: 42}
+
+			token, err := tt.cfg.PrevCursor(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cur, err := tt.cfg.Decode(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cur.Backward {
+				t.Errorf("want backward cursor. got forward")
+			}
+
+			var got position
+			if err := cur.DecodeKey(&got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got != want {
+				t.Errorf("want %+v. got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestCursorEncrypted(t *testing.T) {
+	cfg := &pagination.Config{Secret: []byte("secret"), EncryptionKey: []byte("0123456789abcdef")}
+
+	token, err := cfg.NextCursor(position{ID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(b), "42") {
+		t.Errorf("want encrypted cursor. got %q", b)
+	}
+}
+
+func TestCursorInvalid(t *testing.T) {
+	cfg := &pagination.Config{Secret: []byte("secret")}
+
+	token, err := cfg.NextCursor(position{ID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := (&pagination.Config{Secret: []byte("other")}).NextCursor(position{ID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// flip a bit of the payload
+	b, _ := base64.RawURLEncoding.DecodeString(token)
+	b[0] ^= 1
+	tampered := base64.RawURLEncoding.EncodeToString(b)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "not base64", token: "not a cursor!"},
+		{name: "too short", token: "abc"},
+		{name: "tampered", token: tampered},
+		{name: "other secret", token: other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := cfg.Decode(tt.token)
+			if err != pagination.ErrInvalidCursor {
+				t.Errorf("want ErrInvalidCursor. got %v", err)
+			}
+		})
+	}
+}
+
+func TestCursorExpired(t *testing.T) {
+	cfg := &pagination.Config{Secret: []byte("secret"), TTL: -time.Hour}
+
+	token, err := cfg.NextCursor(position{ID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a negative TTL does not set an expiry
+	if _, err := cfg.Decode(token); err != nil {
+		t.Fatalf("want no error. got %v", err)
+	}
+
+	now := time.Now()
+	cfg.SetNow(func() time.Time { return now })
+	cfg.TTL = time.Minute
+
+	token, err = cfg.NextCursor(position{ID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := cfg.Decode(token); err != nil {
+		t.Fatalf("want no error until the TTL has passed. got %v", err)
+	}
+
+	now = now.Add(time.Second)
+
+	_, err = cfg.Decode(token)
+	if err != pagination.ErrExpiredCursor {
+		t.Errorf("want ErrExpiredCursor. got %v", err)
+	}
+
+	if status := pagination.ErrExpiredCursor.Status(); status != 400 {
+		t.Errorf("want status 400. got %d", status)
+	}
+}
+
+func TestCursorInvalidEncryptionKey(t *testing.T) {
+	cfg := &pagination.Config{Secret: []byte("secret"), EncryptionKey: []byte("0123456789abcdef")}
+
+	token, err := cfg.NextCursor(position{ID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.EncryptionKey = []byte("short")
+
+	_, err = cfg.Decode(token)
+	if err == nil {
+		t.Fatal("want error. got nil")
+	}
+
+	// a configuration error, not the client's
+	rr := httptest.NewRecorder()
+	httputils.ServeError(rr, err)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("want status code %d. got %d", http.StatusInternalServerError, rr.Code)
+	}
+}
+
+func TestCursorNoSecret(t *testing.T) {
+	cfg := &pagination.Config{}
+
+	if _, err := cfg.NextCursor(1); err == nil {
+		t.Errorf("want error. got nil")
+	}
+}
@@ -0,0 +1,8 @@
+package pagination
+
+import "time"
+
+// SetNow sets the clock of cursor expiry
+func (c *Config) SetNow(now func() time.Time) {
+	c.now = now
+}
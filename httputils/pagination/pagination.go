@@ -0,0 +1,234 @@
+// Package pagination parses pagination parameters and builds paginated responses.
+//
+// Cursor pagination uses opaque cursor tokens holding the position of a page, such as the sort
+// key of its last item. Tokens are signed with HMAC-SHA256 so clients cannot forge them, and are
+// optionally encrypted with AES-GCM so clients cannot read them either. Page and size parameters
+// are supported for endpoints that cannot paginate by cursor.
+package pagination
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils"
+)
+
+var (
+	link = http.CanonicalHeaderKey("Link")
+)
+
+// Error is the APIError returned for invalid pagination parameters
+type Error struct {
+	code string
+	msg  string
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// Status is 400 Bad Request
+func (e *Error) Status() int {
+	return http.StatusBadRequest
+}
+
+// Message returns the message of the error
+func (e *Error) Message() string {
+	return e.msg
+}
+
+// Code returns "invalid_cursor" for invalid or expired cursors, and "bad_request" otherwise
+func (e *Error) Code() string {
+	return e.code
+}
+
+var (
+	// ErrInvalidCursor is returned for cursors that were not issued by the Config
+	ErrInvalidCursor = &Error{code: "invalid_cursor", msg: "cursor is invalid"}
+	// ErrExpiredCursor is returned for cursors older than Config.TTL
+	ErrExpiredCursor = &Error{code: "invalid_cursor", msg: "cursor has expired"}
+)
+
+// Config configures pagination
+type Config struct {
+	// DefaultLimit is the page size when none is requested. Defaults to 20.
+	DefaultLimit int
+	// MaxLimit bounds the page size requested. Defaults to 100.
+	MaxLimit int
+	// Secret is the HMAC key signing cursors. It is required for cursor pagination.
+	Secret []byte
+	// EncryptionKey encrypts cursors with AES-GCM if set. It must be 16, 24 or 32 bytes long.
+	EncryptionKey []byte
+	// TTL is how long cursors are valid. Zero never expires them.
+	TTL time.Duration
+
+	// now is the clock of cursor expiry, time.Now unless set by tests
+	now func() time.Time
+}
+
+func (c *Config) defaultLimit() int {
+	if c.DefaultLimit <= 0 {
+		return 20
+	}
+	return c.DefaultLimit
+}
+
+func (c *Config) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+func (c *Config) maxLimit() int {
+	if c.MaxLimit <= 0 {
+		return 100
+	}
+	return c.MaxLimit
+}
+
+// maxOffset bounds Request.Offset, so it fits in an int on every platform
+const maxOffset = math.MaxInt32
+
+// Request is a parsed pagination request
+type Request struct {
+	// Limit is the number of items of the page
+	Limit int
+	// Cursor is the position of the page requested with the cursor parameter, or nil for the first page
+	Cursor *Cursor
+	// Page is the 1-based page number requested with the page parameter, or 0 if there is none
+	Page int
+}
+
+// Offset returns the number of items before the requested page, for page and size pagination
+func (req *Request) Offset() int {
+	if req.Page <= 1 {
+		return 0
+	}
+	return (req.Page - 1) * req.Limit
+}
+
+// Parse parses the pagination query parameters of r: limit (or its alias size) with cursor, or page.
+// The limit defaults to DefaultLimit and is capped at MaxLimit. Invalid parameters, including pages
+// too large for their offset to fit in 32 bits, are a 400 *Error.
+func (c *Config) Parse(r *http.Request) (*Request, error) {
+	q := r.URL.Query()
+
+	req := &Request{Limit: c.defaultLimit()}
+
+	limit := q.Get("limit")
+	if limit == "" {
+		limit = q.Get("size")
+	}
+
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return nil, &Error{code: "bad_request", msg: "limit must be a positive integer"}
+		}
+
+		req.Limit = n
+		if n > c.maxLimit() {
+			req.Limit = c.maxLimit()
+		}
+	}
+
+	cursor, page := q.Get("cursor"), q.Get("page")
+	if cursor != "" && page != "" {
+		return nil, &Error{code: "bad_request", msg: "cursor and page must not be used together"}
+	}
+
+	if cursor != "" {
+		cur, err := c.Decode(cursor)
+		if err != nil {
+			return nil, err
+		}
+		req.Cursor = cur
+	}
+
+	if page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return nil, &Error{code: "bad_request", msg: "page must be a positive integer"}
+		}
+
+		// bound the offset, so it does not overflow
+		if max := maxOffset/req.Limit + 1; n > max {
+			return nil, &Error{code: "bad_request", msg: fmt.Sprintf("page must not be greater than %d", max)}
+		}
+		req.Page = n
+	}
+
+	return req, nil
+}
+
+// Page is the envelope of a page of a list response
+type Page struct {
+	Data interface{} `json:"data"`
+	// NextCursor and PrevCursor are empty on the last and first page
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// ServePage serves p with a Link header to its next and previous pages
+func ServePage(w http.ResponseWriter, r *http.Request, p *Page) {
+	SetLinkHeader(w, r, p)
+	httputils.ServeJSON(w, http.StatusOK, p)
+}
+
+// SetLinkHeader sets the RFC 8288 Link header of a response with the next and previous pages of p.
+// The links are r's URL with its cursor parameter replaced.
+func SetLinkHeader(w http.ResponseWriter, r *http.Request, p *Page) {
+	var links []string
+
+	if p.NextCursor != "" {
+		links = append(links, linkTo(r, "cursor", p.NextCursor, "next"))
+	}
+
+	if p.PrevCursor != "" {
+		links = append(links, linkTo(r, "cursor", p.PrevCursor, "prev"))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set(link, strings.Join(links, ", "))
+	}
+}
+
+// SetPageLinkHeader sets the RFC 8288 Link header of a response to the page of req,
+// for page and size pagination. hasNext tells whether there is a next page.
+func SetPageLinkHeader(w http.ResponseWriter, r *http.Request, req *Request, hasNext bool) {
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+
+	var links []string
+
+	if hasNext {
+		links = append(links, linkTo(r, "page", strconv.Itoa(page+1), "next"))
+	}
+
+	if page > 1 {
+		links = append(links, linkTo(r, "page", strconv.Itoa(page-1), "prev"))
+		links = append(links, linkTo(r, "page", "1", "first"))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set(link, strings.Join(links, ", "))
+	}
+}
+
+// linkTo returns a link value to r's URL with param set to value.
+// The URL is relative, as the host of r may be the one of a proxy.
+func linkTo(r *http.Request, param, value, rel string) string {
+	q := r.URL.Query()
+	q.Set(param, value)
+
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	return fmt.Sprintf("<%s>; rel=%q", u.String(), rel)
+}
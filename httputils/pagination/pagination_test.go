@@ -0,0 +1,155 @@
+package pagination_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils/pagination"
+)
+
+func TestParse(t *testing.T) {
+	cfg := &pagination.Config{Secret: []byte("secret"), DefaultLimit: 10, MaxLimit: 50}
+
+	cursor, err := cfg.NextCursor(position{ID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantLimit  int
+		wantPage   int
+		wantOffset int
+		wantCursor bool
+		wantCode   string
+	}{
+		{name: "defaults", query: "", wantLimit: 10},
+		{name: "limit", query: "limit=5", wantLimit: 5},
+		{name: "limit capped", query: "limit=500", wantLimit: 50},
+		{name: "cursor", query: "limit=5&cursor=" + cursor, wantLimit: 5, wantCursor: true},
+		{name: "page and size", query: "page=3&size=5", wantLimit: 5, wantPage: 3, wantOffset: 10},
+		{name: "invalid limit", query: "limit=abc", wantCode: "bad_request"},
+		{name: "zero limit", query: "limit=0", wantCode: "bad_request"},
+		{name: "invalid page", query: "page=-1", wantCode: "bad_request"},
+		{name: "max page", query: "page=42949673&size=50", wantLimit: 50, wantPage: 42949673, wantOffset: 2147483600},
+		{name: "page out of range", query: "page=42949674&size=50", wantCode: "bad_request"},
+		{name: "page overflow", query: "page=9223372036854775807", wantCode: "bad_request"},
+		{name: "cursor and page", query: "page=2&cursor=" + cursor, wantCode: "bad_request"},
+		{name: "invalid cursor", query: "cursor=abc", wantCode: "invalid_cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/things?"+tt.query, nil)
+
+			req, err := cfg.Parse(r)
+			if tt.wantCode != "" {
+				perr, ok := err.(*pagination.Error)
+				if !ok {
+					t.Fatalf("want *pagination.Error. got %v", err)
+				}
+				if perr.Code() != tt.wantCode {
+					t.Errorf("want code %s. got %s", tt.wantCode, perr.Code())
+				}
+				if perr.Status() != http.StatusBadRequest {
+					t.Errorf("want status 400. got %d", perr.Status())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Limit != tt.wantLimit {
+				t.Errorf("want limit %d. got %d", tt.wantLimit, req.Limit)
+			}
+
+			if req.Page != tt.wantPage {
+				t.Errorf("want page %d. got %d", tt.wantPage, req.Page)
+			}
+
+			if req.Offset() != tt.wantOffset {
+				t.Errorf("want offset %d. got %d", tt.wantOffset, req.Offset())
+			}
+
+			if (req.Cursor != nil) != tt.wantCursor {
+				t.Errorf("want cursor %t. got %v", tt.wantCursor, req.Cursor)
+			}
+		})
+	}
+}
+
+func TestServePage(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/things?limit=2&status=open&cursor=old", nil)
+	rr := httptest.NewRecorder()
+
+	pagination.ServePage(rr, r, &pagination.Page{
+		Data:       []int{1, 2},
+		NextCursor: "next",
+		PrevCursor: "prev",
+	})
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("want status 200. got %d", rr.Code)
+	}
+
+	wantLink := `</v1/things?cursor=next&limit=2&status=open>; rel="next", </v1/things?cursor=prev&limit=2&status=open>; rel="prev"`
+	if got := rr.Header().Get("Link"); got != wantLink {
+		t.Errorf("want Link %s. got %s", wantLink, got)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if body["next_cursor"] != "next" || body["prev_cursor"] != "prev" {
+		t.Errorf("want next and prev cursors. got %v", body)
+	}
+}
+
+func TestServePageLast(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/things", nil)
+	rr := httptest.NewRecorder()
+
+	pagination.ServePage(rr, r, &pagination.Page{Data: []int{}})
+
+	if got := rr.Header().Get("Link"); got != "" {
+		t.Errorf("want no Link. got %s", got)
+	}
+
+	want := `{"data":[]}`
+	if got := rr.Body.String(); got != want {
+		t.Errorf("want body %s. got %s", want, got)
+	}
+}
+
+func TestSetPageLinkHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    int
+		hasNext bool
+		want    string
+	}{
+		{name: "first", page: 0, hasNext: true, want: `</v1/things?page=2&size=5>; rel="next"`},
+		{name: "middle", page: 2, hasNext: true, want: `</v1/things?page=3&size=5>; rel="next", </v1/things?page=1&size=5>; rel="prev", </v1/things?page=1&size=5>; rel="first"`},
+		{name: "only", page: 1, hasNext: false, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/things?size=5", nil)
+			rr := httptest.NewRecorder()
+
+			pagination.SetPageLinkHeader(rr, r, &pagination.Request{Limit: 5, Page: tt.page}, tt.hasNext)
+
+			if got := rr.Header().Get("Link"); got != tt.want {
+				t.Errorf("want Link %s. got %s", tt.want, got)
+			}
+		})
+	}
+}
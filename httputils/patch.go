@@ -0,0 +1,520 @@
+package httputils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/golang/gddo/httputil/header"
+)
+
+const (
+	// ContentTypeJSONPatch is the content type of JSON Patch documents, RFC 6902
+	ContentTypeJSONPatch = "application/json-patch+json"
+	// ContentTypeMergePatch is the content type of JSON Merge Patch documents, RFC 7396
+	ContentTypeMergePatch = "application/merge-patch+json"
+)
+
+// codePatchFailed is the code of every 422 error of DecodePatch, for operations that fail and patched
+// resources that do not decode
+const codePatchFailed = "patch_failed"
+
+// PatchError is the APIError returned when a JSON Patch operation cannot be applied.
+// Its status is 400 if the operation is malformed and 422 if it does not apply to the resource.
+type PatchError struct {
+	// Index is the position of the operation in the patch
+	Index int
+	// Op is the operation, such as "add"
+	Op string
+	// Pointer is the JSON pointer of the operation that failed
+	Pointer string
+	// Reason describes the failure
+	Reason string
+
+	status int
+}
+
+func (pe *PatchError) Error() string {
+	return pe.Message()
+}
+
+// Status returns 400 or 422
+func (pe *PatchError) Status() int {
+	return pe.status
+}
+
+// Message describes the failed operation
+func (pe *PatchError) Message() string {
+	return fmt.Sprintf("patch operation %d (%s) failed at %q: %s", pe.Index, pe.Op, pe.Pointer, pe.Reason)
+}
+
+// Code returns "bad_request" for malformed operations and "patch_failed" otherwise
+func (pe *PatchError) Code() string {
+	if pe.status == http.StatusBadRequest {
+		return "bad_request"
+	}
+	return codePatchFailed
+}
+
+// DecodePatch applies the JSON Patch or JSON Merge Patch of the request body to dst, a pointer to the resource.
+// The patch type is chosen by the Content-Type header, and the body is limited to 1MB like DecodeJSONBody.
+// dst is replaced by the patched resource, which is decoded strictly: fields added by the patch that dst does
+// not have result in a 422 error, and dst is left unchanged on error. Every 422 error has the "patch_failed" code.
+func DecodePatch(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(dst)}
+	}
+
+	value, _ := header.ParseValueAndParams(r.Header, ContentType)
+	if value != ContentTypeJSONPatch && value != ContentTypeMergePatch {
+		msg := fmt.Sprintf("%s header is not %s or %s", ContentType, ContentTypeJSONPatch, ContentTypeMergePatch)
+		return &malformedRequest{status: http.StatusUnsupportedMediaType, msg: msg}
+	}
+
+	patch, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, oneMB))
+	if err != nil {
+		if isBodyTooLarge(err) {
+			return &malformedRequest{status: http.StatusRequestEntityTooLarge, msg: msgRequestTooLarge}
+		}
+		return err
+	}
+
+	doc, err := json.Marshal(dst)
+	if err != nil {
+		return err
+	}
+
+	if value == ContentTypeJSONPatch {
+		doc, err = ApplyJSONPatch(doc, patch)
+	} else {
+		doc, err = ApplyMergePatch(doc, patch)
+	}
+	if err != nil {
+		return err
+	}
+
+	// decode into a new value, so fields removed by the patch are zeroed
+	v := reflect.New(rv.Type().Elem())
+
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v.Interface()); err != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+
+		switch {
+		case errors.As(err, &unmarshalTypeError):
+			msg := fmt.Sprintf("patched resource contains an invalid value for the %q field", unmarshalTypeError.Field)
+			return &malformedRequest{status: http.StatusUnprocessableEntity, msg: msg}
+
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			msg := fmt.Sprintf("patched resource contains unknown field %s", fieldName)
+			return &malformedRequest{status: http.StatusUnprocessableEntity, msg: msg}
+
+		default:
+			return err
+		}
+	}
+
+	rv.Elem().Set(v.Elem())
+	return nil
+}
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyJSONPatch applies a JSON Patch, RFC 6902, to the JSON document doc.
+// Operations that fail return a *PatchError.
+func ApplyJSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []patchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		msg := "request body must be a JSON Patch array of operations"
+		return nil, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	root, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		root, err = applyOperation(root, op)
+		if err != nil {
+			var pe *PatchError
+			if errors.As(err, &pe) {
+				pe.Index = i
+				pe.Op = op.Op
+			}
+			return nil, err
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func applyOperation(root interface{}, op patchOperation) (interface{}, error) {
+	if op.Path == nil {
+		return nil, malformedOperation("", `missing "path"`)
+	}
+
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		// a null value is a RawMessage of "null", a missing one is empty
+		if len(op.Value) == 0 {
+			return nil, malformedOperation(*op.Path, `missing "value"`)
+		}
+		if value, err = decodeJSON(op.Value); err != nil {
+			return nil, malformedOperation(*op.Path, "invalid value")
+		}
+	}
+
+	var from []string
+	switch op.Op {
+	case "move", "copy":
+		if op.From == nil {
+			return nil, malformedOperation(*op.Path, `missing "from"`)
+		}
+		if from, err = parsePointer(*op.From); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return add(root, path, value, false, *op.Path)
+
+	case "remove":
+		root, _, err := remove(root, path, *op.Path)
+		return root, err
+
+	case "replace":
+		return add(root, path, value, true, *op.Path)
+
+	case "move":
+		if *op.From == *op.Path {
+			return root, nil
+		}
+		if strings.HasPrefix(*op.Path, *op.From+"/") {
+			return nil, failedOperation(*op.Path, "cannot move a value into one of its children")
+		}
+
+		root, value, err := remove(root, from, *op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value, false, *op.Path)
+
+	case "copy":
+		value, err := get(root, from, *op.From)
+		if err != nil {
+			return nil, err
+		}
+		// copy the value, so later operations on either copy do not change the other
+		b, _ := json.Marshal(value)
+		value, _ = decodeJSON(b)
+		return add(root, path, value, false, *op.Path)
+
+	case "test":
+		current, err := get(root, path, *op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !equalJSON(current, value) {
+			return nil, failedOperation(*op.Path, "value does not match")
+		}
+		return root, nil
+
+	default:
+		return nil, malformedOperation(*op.Path, fmt.Sprintf("unknown operation %q", op.Op))
+	}
+}
+
+// add adds or, if replace is set, replaces the value at tokens
+func add(node interface{}, tokens []string, value interface{}, replace bool, pointer string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	tok, last := tokens[0], len(tokens) == 1
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[tok]
+		if !ok && (!last || replace) {
+			return nil, failedOperation(pointer, "path does not exist")
+		}
+
+		if last {
+			n[tok] = value
+			return n, nil
+		}
+
+		child, err := add(child, tokens[1:], value, replace, pointer)
+		if err != nil {
+			return nil, err
+		}
+		n[tok] = child
+		return n, nil
+
+	case []interface{}:
+		if last && !replace {
+			i := len(n)
+			if tok != "-" {
+				var err error
+				if i, err = arrayIndex(tok, len(n)+1, pointer); err != nil {
+					return nil, err
+				}
+			}
+
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+			return n, nil
+		}
+
+		i, err := arrayIndex(tok, len(n), pointer)
+		if err != nil {
+			return nil, err
+		}
+
+		if n[i], err = add(n[i], tokens[1:], value, replace, pointer); err != nil {
+			return nil, err
+		}
+		return n, nil
+
+	default:
+		return nil, failedOperation(pointer, "path does not exist")
+	}
+}
+
+// remove removes the value at tokens and returns it
+func remove(node interface{}, tokens []string, pointer string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil, failedOperation(pointer, "cannot remove the whole document")
+	}
+
+	tok, last := tokens[0], len(tokens) == 1
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[tok]
+		if !ok {
+			return nil, nil, failedOperation(pointer, "path does not exist")
+		}
+
+		if last {
+			delete(n, tok)
+			return n, child, nil
+		}
+
+		child, removed, err := remove(child, tokens[1:], pointer)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[tok] = child
+		return n, removed, nil
+
+	case []interface{}:
+		i, err := arrayIndex(tok, len(n), pointer)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if last {
+			removed := n[i]
+			return append(n[:i], n[i+1:]...), removed, nil
+		}
+
+		child, removed, err := remove(n[i], tokens[1:], pointer)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[i] = child
+		return n, removed, nil
+
+	default:
+		return nil, nil, failedOperation(pointer, "path does not exist")
+	}
+}
+
+// get returns the value at tokens
+func get(node interface{}, tokens []string, pointer string) (interface{}, error) {
+	for _, tok := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[tok]
+			if !ok {
+				return nil, failedOperation(pointer, "path does not exist")
+			}
+			node = child
+
+		case []interface{}:
+			i, err := arrayIndex(tok, len(n), pointer)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+
+		default:
+			return nil, failedOperation(pointer, "path does not exist")
+		}
+	}
+
+	return node, nil
+}
+
+// parsePointer splits a JSON pointer, RFC 6901, into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, malformedOperation(pointer, "JSON pointer must start with /")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, tok := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+	}
+
+	return tokens, nil
+}
+
+// arrayIndex parses tok as an index of an array of length n
+func arrayIndex(tok string, n int, pointer string) (int, error) {
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || (len(tok) > 1 && tok[0] == '0') {
+		return 0, failedOperation(pointer, fmt.Sprintf("invalid array index %q", tok))
+	}
+
+	if i >= n {
+		return 0, failedOperation(pointer, fmt.Sprintf("array index %d out of bounds", i))
+	}
+
+	return i, nil
+}
+
+func malformedOperation(pointer, reason string) *PatchError {
+	return &PatchError{Pointer: pointer, Reason: reason, status: http.StatusBadRequest}
+}
+
+func failedOperation(pointer, reason string) *PatchError {
+	return &PatchError{Pointer: pointer, Reason: reason, status: http.StatusUnprocessableEntity}
+}
+
+// ApplyMergePatch applies a JSON Merge Patch, RFC 7396, to the JSON document doc
+func ApplyMergePatch(doc, patch []byte) ([]byte, error) {
+	p, err := decodeJSON(patch)
+	if err != nil {
+		msg := "request body contains badly-formed JSON"
+		return nil, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	}
+
+	root, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergePatch(root, p))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+
+	return t
+}
+
+// decodeJSON decodes b keeping numbers as json.Number, so they are not rounded through float64
+func decodeJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("json: trailing data after top-level value")
+	}
+
+	return v, nil
+}
+
+// equalJSON compares decoded JSON values, comparing numbers by value
+func equalJSON(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aErr := a.Float64()
+		bf, bErr := b.Float64()
+		return aErr == nil && bErr == nil && af == bf
+
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			bv, ok := b[k]
+			if !ok || !equalJSON(v, bv) {
+				return false
+			}
+		}
+		return true
+
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalJSON(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return a == b
+	}
+}
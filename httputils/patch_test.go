@@ -0,0 +1,308 @@
+package httputils_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/httputils"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	t.Parallel()
+
+	doc := `{"name": "thing", "tags": ["a", "b"], "owner": {"id": 12345678901234567890}, "a/b": 1}`
+
+	tests := []struct {
+		name        string
+		patch       string
+		want        string
+		wantStatus  int
+		wantPointer string
+	}{
+		{
+			name: "Add",
+			patch: `[
+				{"op": "add", "path": "/description", "value": null},
+				{"op": "add", "path": "/tags/1", "value": "c"},
+				{"op": "add", "path": "/tags/-", "value": "d"}
+			]`,
+			want: `{"name": "thing", "description": null, "tags": ["a", "c", "b", "d"], "owner": {"id": 12345678901234567890}, "a/b": 1}`,
+		},
+		{
+			name:  "Remove",
+			patch: `[{"op": "remove", "path": "/tags/0"}, {"op": "remove", "path": "/a~1b"}]`,
+			want:  `{"name": "thing", "tags": ["b"], "owner": {"id": 12345678901234567890}}`,
+		},
+		{
+			name:  "Replace",
+			patch: `[{"op": "replace", "path": "/owner/id", "value": 1}]`,
+			want:  `{"name": "thing", "tags": ["a", "b"], "owner": {"id": 1}, "a/b": 1}`,
+		},
+		{
+			name:  "Move",
+			patch: `[{"op": "move", "from": "/name", "path": "/owner/name"}]`,
+			want:  `{"tags": ["a", "b"], "owner": {"id": 12345678901234567890, "name": "thing"}, "a/b": 1}`,
+		},
+		{
+			name:  "Copy",
+			patch: `[{"op": "copy", "from": "/tags", "path": "/labels"}, {"op": "add", "path": "/labels/-", "value": "c"}]`,
+			want:  `{"name": "thing", "tags": ["a", "b"], "labels": ["a", "b", "c"], "owner": {"id": 12345678901234567890}, "a/b": 1}`,
+		},
+		{
+			name:  "Test",
+			patch: `[{"op": "test", "path": "/owner", "value": {"id": 12345678901234567890}}, {"op": "test", "path": "/a~1b", "value": 1.0}]`,
+			want:  doc,
+		},
+		{
+			name:        "Test Failed",
+			patch:       `[{"op": "test", "path": "/name", "value": "other"}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantPointer: "/name",
+		},
+		{
+			name:        "Missing Path",
+			patch:       `[{"op": "test", "path": "/name", "value": "thing"}, {"op": "remove", "path": "/missing"}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantPointer: "/missing",
+		},
+		{
+			name:        "Replace Missing",
+			patch:       `[{"op": "replace", "path": "/missing", "value": 1}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantPointer: "/missing",
+		},
+		{
+			name:        "Index Out Of Bounds",
+			patch:       `[{"op": "add", "path": "/tags/3", "value": "c"}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantPointer: "/tags/3",
+		},
+		{
+			name:        "Move Into Child",
+			patch:       `[{"op": "move", "from": "/owner", "path": "/owner/id"}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantPointer: "/owner/id",
+		},
+		{
+			name:        "Unknown Operation",
+			patch:       `[{"op": "increment", "path": "/owner/id"}]`,
+			wantStatus:  http.StatusBadRequest,
+			wantPointer: "/owner/id",
+		},
+		{
+			name:        "Missing Value",
+			patch:       `[{"op": "add", "path": "/name"}]`,
+			wantStatus:  http.StatusBadRequest,
+			wantPointer: "/name",
+		},
+		{
+			name:        "Invalid Pointer",
+			patch:       `[{"op": "remove", "path": "name"}]`,
+			wantStatus:  http.StatusBadRequest,
+			wantPointer: "name",
+		},
+		{
+			name:       "Not An Array",
+			patch:      `{"op": "remove", "path": "/name"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := httputils.ApplyJSONPatch([]byte(doc), []byte(tt.patch))
+
+			if tt.wantStatus != 0 {
+				var apiError httputils.APIError
+				if !errors.As(err, &apiError) || apiError.Status() != tt.wantStatus {
+					t.Fatalf("want APIError with status code %d. got %v", tt.wantStatus, err)
+				}
+
+				var patchError *httputils.PatchError
+				if tt.wantPointer != "" && (!errors.As(err, &patchError) || patchError.Pointer != tt.wantPointer) {
+					t.Errorf("want PatchError at %s. got %v", tt.wantPointer, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error. got %v", err)
+			}
+
+			assertJSONEqual(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	t.Parallel()
+
+	// https://datatracker.ietf.org/doc/html/rfc7396#section-3
+	doc := `{"title": "Goodbye!", "author": {"givenName": "John", "familyName": "Doe"}, "tags": ["example", "sample"], "content": "This will be unchanged"}`
+	patch := `{"title": "Hello!", "phoneNumber": "+01-123-456-7890", "author": {"familyName": null}, "tags": ["example"]}`
+	want := `{"title": "Hello!", "author": {"givenName": "John"}, "tags": ["example"], "content": "This will be unchanged", "phoneNumber": "+01-123-456-7890"}`
+
+	got, err := httputils.ApplyMergePatch([]byte(doc), []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertJSONEqual(t, want, string(got))
+
+	if _, err := httputils.ApplyMergePatch([]byte(doc), []byte(`{`)); err == nil {
+		t.Errorf("want error for badly-formed patch")
+	}
+
+	if _, err := httputils.ApplyMergePatch([]byte(doc), []byte(`{"title": "Hello!"} {}`)); err == nil {
+		t.Errorf("want error for patch with trailing data")
+	}
+}
+
+type patchResource struct {
+	Name        string   `json:"name"`
+	Description *string  `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+func TestDecodePatch(t *testing.T) {
+	t.Parallel()
+
+	description := "a thing"
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        patchResource
+		wantStatus  int
+		wantCode    string
+	}{
+		{
+			name:        "JSON Patch",
+			contentType: httputils.ContentTypeJSONPatch,
+			body:        `[{"op": "remove", "path": "/description"}, {"op": "add", "path": "/tags/-", "value": "b"}]`,
+			want:        patchResource{Name: "thing", Tags: []string{"a", "b"}},
+		},
+		{
+			name:        "Merge Patch",
+			contentType: httputils.ContentTypeMergePatch + "; charset=utf-8",
+			body:        `{"description": null, "name": "other"}`,
+			want:        patchResource{Name: "other", Tags: []string{"a"}},
+		},
+		{
+			name:        "Unknown Field",
+			contentType: httputils.ContentTypeMergePatch,
+			body:        `{"color": "red"}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    "patch_failed",
+		},
+		{
+			name:        "Invalid Value",
+			contentType: httputils.ContentTypeMergePatch,
+			body:        `{"name": 1}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    "patch_failed",
+		},
+		{
+			name:        "Failed Operation",
+			contentType: httputils.ContentTypeJSONPatch,
+			body:        `[{"op": "remove", "path": "/tags/1"}]`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    "patch_failed",
+		},
+		{
+			name:        "Trailing Data",
+			contentType: httputils.ContentTypeMergePatch,
+			body:        `{"name": "other"} {"name": "another"}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "JSON",
+			contentType: httputils.ContentTypeJSON,
+			body:        `{"name": "other"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "Too Large",
+			contentType: httputils.ContentTypeMergePatch,
+			body:        `{"name": "` + strings.Repeat("a", 1048576) + `"}`,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			original := patchResource{Name: "thing", Description: &description, Tags: []string{"a"}}
+			dst := original
+
+			req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			err := httputils.DecodePatch(httptest.NewRecorder(), req, &dst)
+
+			if tt.wantStatus != 0 {
+				var apiError httputils.APIError
+				if !errors.As(err, &apiError) || apiError.Status() != tt.wantStatus {
+					t.Errorf("want APIError with status code %d. got %v", tt.wantStatus, err)
+				}
+
+				if tt.wantCode != "" && (apiError == nil || apiError.Code() != tt.wantCode) {
+					t.Errorf("want code %s. got %v", tt.wantCode, err)
+				}
+
+				if !reflect.DeepEqual(dst, original) {
+					t.Errorf("want resource to be unchanged. got %+v", dst)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("want no error. got %v", err)
+			}
+
+			if !reflect.DeepEqual(dst, tt.want) {
+				t.Errorf("want %+v. got %+v", tt.want, dst)
+			}
+		})
+	}
+}
+
+func TestDecodePatchNonPointer(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(`{"name": "other"}`))
+	req.Header.Set("Content-Type", httputils.ContentTypeMergePatch)
+
+	if err := httputils.DecodePatch(httptest.NewRecorder(), req, patchResource{}); err == nil {
+		t.Errorf("want error for non-pointer resource")
+	}
+}
+
+func assertJSONEqual(t *testing.T, want, got string) {
+	t.Helper()
+
+	// numbers are compared as written, so precision loss is detected
+	var wantValue, gotValue interface{}
+	dec := json.NewDecoder(strings.NewReader(want))
+	dec.UseNumber()
+	dec.Decode(&wantValue)
+
+	dec = json.NewDecoder(strings.NewReader(got))
+	dec.UseNumber()
+	dec.Decode(&gotValue)
+
+	if !reflect.DeepEqual(wantValue, gotValue) {
+		t.Errorf("want %s. got %s", want, got)
+	}
+}
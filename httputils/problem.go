@@ -0,0 +1,70 @@
+package httputils
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang/gddo/httputil/header"
+)
+
+// ContentTypeProblemJSON is a constant for RFC 7807 problem details Header Type
+const ContentTypeProblemJSON = "application/problem+json"
+
+// ProblemDetails is the RFC 7807 application/problem+json format of error responses.
+// Code, RequestID and Details are extension members with the values of APIErrorResponse.
+type ProblemDetails struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	Code      string      `json:"code"`
+	RequestID string      `json:"request_id,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+func newProblem(status int, resp APIErrorResponse) ProblemDetails {
+	return ProblemDetails{
+		// problems are identified by their code rather than by a URI
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    resp.Message,
+		Code:      resp.Code,
+		RequestID: resp.RequestID,
+		Details:   resp.Details,
+	}
+}
+
+var problem int32
+
+// SetProblemJSON enables or disables application/problem+json error responses for all requests.
+// When disabled, ServeRequestError still serves them to requests accepting application/problem+json.
+func SetProblemJSON(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&problem, v)
+}
+
+// problemJSON returns true if the error response to r, which may be nil, should be application/problem+json
+func problemJSON(r *http.Request) bool {
+	if atomic.LoadInt32(&problem) == 1 {
+		return true
+	}
+
+	if r == nil {
+		return false
+	}
+
+	// only an explicit application/problem+json opts in, not wildcards
+	for _, spec := range header.ParseAccept(r.Header, Accept) {
+		if strings.EqualFold(spec.Value, ContentTypeProblemJSON) && spec.Q > 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/viaduct-ai/vgo/jwtutils"
+)
+
+var (
+	// EnvoyExternalAddress is the header of the client address set by an edge Envoy, for ByForwardedIP
+	EnvoyExternalAddress = http.CanonicalHeaderKey("X-Envoy-External-Address")
+)
+
+// KeyFunc identifies the caller of a request. An empty key means the caller is unknown.
+type KeyFunc func(r *http.Request) string
+
+// ByIP identifies callers by the IP address of the remote end of the connection.
+// Behind a proxy, that is the address of the proxy; use ByForwardedIP instead.
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// ByForwardedIP identifies callers by the IP address in header, such as EnvoyExternalAddress, falling back
+// to the remote address if it is missing. Clients can set any header, so only use it behind a proxy
+// that always overwrites header with the address it received the request from.
+func ByForwardedIP(header string) KeyFunc {
+	header = http.CanonicalHeaderKey(header)
+
+	return func(r *http.Request) string {
+		if addr := r.Header.Get(header); addr != "" {
+			return "ip:" + addr
+		}
+
+		return ByIP(r)
+	}
+}
+
+// ByClaim identifies callers by a claim of their JWT, such as "sub".
+// The token is not verified, it is assumed to be verified upstream.
+func ByClaim(name string) KeyFunc {
+	return func(r *http.Request) string {
+		// try parse claims from request, ignore errors
+		claims, _ := jwtutils.ParseUnverifiedTokenClaimsFromRequest(r)
+
+		v, ok := claims[name]
+		if !ok || v == nil || v == "" {
+			return ""
+		}
+
+		return fmt.Sprintf("%s:%v", name, v)
+	}
+}
+
+// ByHeader identifies callers by a request header, such as an API key
+func ByHeader(name string) KeyFunc {
+	name = http.CanonicalHeaderKey(name)
+
+	return func(r *http.Request) string {
+		v := r.Header.Get(name)
+		if v == "" {
+			return ""
+		}
+
+		return name + ":" + v
+	}
+}
+
+// FirstOf identifies callers by the first of keys returning a non-empty key,
+// for example the JWT subject with a fallback to the IP address.
+func FirstOf(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, key := range keys {
+			if k := key(r); k != "" {
+				return k
+			}
+		}
+
+		return ""
+	}
+}
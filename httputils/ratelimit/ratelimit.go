@@ -0,0 +1,178 @@
+// Package ratelimit limits the rate of requests per caller.
+//
+// A Config combines a KeyFunc identifying the caller, such as ByIP or ByClaim, with
+// a default Limiter and optional per route Limiters. Limiters are either an in-memory
+// TokenBucket or a SlidingWindow backed by a Store, which can be shared between
+// instances. The net/http and gin rate limiting middlewares both use a Config.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/viaduct-ai/vgo/log"
+)
+
+var (
+	// Rate limit headers of https://datatracker.ietf.org/doc/draft-ietf-httpapi-ratelimit-headers/
+	limitHeader     = http.CanonicalHeaderKey("RateLimit-Limit")
+	remainingHeader = http.CanonicalHeaderKey("RateLimit-Remaining")
+	resetHeader     = http.CanonicalHeaderKey("RateLimit-Reset")
+	retryAfter      = http.CanonicalHeaderKey("Retry-After")
+)
+
+// Rate is a number of requests allowed per period
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+func (r Rate) validate() error {
+	if r.Limit <= 0 || r.Period <= 0 {
+		return fmt.Errorf("invalid rate %d per %s: limit and period must be positive", r.Limit, r.Period)
+	}
+	return nil
+}
+
+// PerSecond returns a Rate of n requests per second
+func PerSecond(n int) Rate {
+	return Rate{Limit: n, Period: time.Second}
+}
+
+// PerMinute returns a Rate of n requests per minute
+func PerMinute(n int) Rate {
+	return Rate{Limit: n, Period: time.Minute}
+}
+
+// PerHour returns a Rate of n requests per hour
+func PerHour(n int) Rate {
+	return Rate{Limit: n, Period: time.Hour}
+}
+
+// Result is the outcome of a rate limit check
+type Result struct {
+	Allowed bool
+	// Limit is the maximum number of requests of the Rate
+	Limit int
+	// Remaining is the number of requests left before the limit is reached
+	Remaining int
+	// Reset is the time until the limit is fully available again
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, if it was not
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether another request of the caller identified by key is allowed
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// Error is the APIError returned for rate limited requests
+type Error struct {
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+// Status is 429 Too Many Requests
+func (e *Error) Status() int {
+	return http.StatusTooManyRequests
+}
+
+// Message tells the caller when to retry
+func (e *Error) Message() string {
+	return fmt.Sprintf("too many requests, retry after %d seconds", seconds(e.RetryAfter))
+}
+
+// Code is "rate_limited"
+func (e *Error) Code() string {
+	return "rate_limited"
+}
+
+// Route applies a Limiter to the requests matching Method and Path
+type Route struct {
+	// Method matches the request method. An empty Method matches any method.
+	Method string
+	// Path matches the request path exactly, or as a prefix if it ends with "*"
+	Path    string
+	Limiter Limiter
+}
+
+func (rt *Route) matches(r *http.Request) bool {
+	if rt.Method != "" && rt.Method != r.Method {
+		return false
+	}
+
+	if strings.HasSuffix(rt.Path, "*") {
+		return strings.HasPrefix(r.URL.Path, strings.TrimSuffix(rt.Path, "*"))
+	}
+
+	return rt.Path == r.URL.Path
+}
+
+// Config configures rate limiting middleware
+type Config struct {
+	// Key identifies the caller. Requests with an empty key are not limited.
+	Key KeyFunc
+	// Limiter applies to requests not matching any of the Routes. A nil Limiter does not limit them.
+	Limiter Limiter
+	// Routes are checked in order, the first match is used. Each Route has its own limits per caller.
+	Routes []Route
+	// Logger logs Limiter errors, if set. Requests are allowed when the Limiter fails.
+	Logger log.Logger
+}
+
+// Check applies the rate limit to r and sets the RateLimit-* headers on w.
+// It returns an *Error and sets Retry-After if the request is limited.
+func (c *Config) Check(w http.ResponseWriter, r *http.Request) error {
+	key := c.Key(r)
+	if key == "" {
+		return nil
+	}
+
+	limiter := c.Limiter
+	for i := range c.Routes {
+		if c.Routes[i].matches(r) {
+			limiter = c.Routes[i].Limiter
+			// namespace the key so every route has its own limits
+			key = c.Routes[i].Method + " " + c.Routes[i].Path + " " + key
+			break
+		}
+	}
+
+	if limiter == nil {
+		return nil
+	}
+
+	res, err := limiter.Allow(r.Context(), key)
+	if err != nil {
+		if c.Logger != nil {
+			c.Logger.Errorf("rate limit check failed, allowing request: %v", err)
+		}
+		return nil
+	}
+
+	h := w.Header()
+	h.Set(limitHeader, strconv.Itoa(res.Limit))
+	h.Set(remainingHeader, strconv.Itoa(res.Remaining))
+	h.Set(resetHeader, strconv.FormatInt(seconds(res.Reset), 10))
+
+	if !res.Allowed {
+		h.Set(retryAfter, strconv.FormatInt(seconds(res.RetryAfter), 10))
+		return &Error{RetryAfter: res.RetryAfter}
+	}
+
+	return nil
+}
+
+// seconds rounds d up to whole seconds
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,208 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/ratelimit"
+	"github.com/viaduct-ai/vgo/testutils"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func newTokenBucket(t *testing.T, rate ratelimit.Rate) *ratelimit.TokenBucket {
+	tb, err := ratelimit.NewTokenBucket(rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tb
+}
+
+func newSlidingWindow(t *testing.T, rate ratelimit.Rate, store ratelimit.Store) *ratelimit.SlidingWindow {
+	sw, err := ratelimit.NewSlidingWindow(rate, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sw
+}
+
+func TestConfigCheck(t *testing.T) {
+	t.Parallel()
+
+	cfg := &ratelimit.Config{
+		Key:     ratelimit.ByHeader("X-Api-Key"),
+		Limiter: newTokenBucket(t, ratelimit.PerHour(2)),
+		Routes: []ratelimit.Route{
+			{
+				Method:  http.MethodPost,
+				Path:    "/v1/exports/*",
+				Limiter: newTokenBucket(t, ratelimit.PerHour(1)),
+			},
+		},
+	}
+
+	check := func(method, path, key string) (*httptest.ResponseRecorder, error) {
+		r := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			r.Header.Set("X-Api-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		return rr, cfg.Check(rr, r)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := check(http.MethodGet, "/v1/users", "a"); err != nil {
+			t.Fatalf("want request %d to be allowed. got %v", i, err)
+		}
+	}
+
+	rr, err := check(http.MethodGet, "/v1/users", "a")
+
+	var apiError httputils.APIError
+	if !errors.As(err, &apiError) || apiError.Status() != http.StatusTooManyRequests || apiError.Code() != "rate_limited" {
+		t.Fatalf("want a 429 APIError. got %v", err)
+	}
+
+	wantHeaders := map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "3600",
+		"Retry-After":         "1800",
+	}
+	for k, want := range wantHeaders {
+		if got := rr.Header().Get(k); got != want {
+			t.Errorf("want %s %s. got %s", k, want, got)
+		}
+	}
+
+	if _, err := check(http.MethodGet, "/v1/users", "b"); err != nil {
+		t.Errorf("want other callers to be allowed. got %v", err)
+	}
+
+	if _, err := check(http.MethodGet, "/v1/users", ""); err != nil {
+		t.Errorf("want unidentified callers not to be limited. got %v", err)
+	}
+
+	// routes have their own limits
+	if _, err := check(http.MethodPost, "/v1/exports/1", "a"); err != nil {
+		t.Errorf("want first export to be allowed. got %v", err)
+	}
+
+	if _, err := check(http.MethodPost, "/v1/exports/2", "a"); err == nil {
+		t.Errorf("want second export to be limited")
+	}
+
+	if _, err := check(http.MethodGet, "/v1/exports/2", "a"); err == nil {
+		t.Errorf("want GET /v1/exports to use the exhausted default limit")
+	}
+}
+
+func TestConfigCheckFailOpen(t *testing.T) {
+	t.Parallel()
+
+	logger := testutils.NewTestLogger()
+	cfg := &ratelimit.Config{
+		Key:     ratelimit.ByIP,
+		Limiter: failingLimiter{},
+		Logger:  logger,
+	}
+
+	if err := cfg.Check(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+		t.Errorf("want request to be allowed when the limiter fails. got %v", err)
+	}
+
+	if len(logger.ErrorLogs) != 1 {
+		t.Errorf("want the failure to be logged. got %v", logger.ErrorLogs)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tb := newTokenBucket(t, ratelimit.Rate{Limit: 2, Period: 100 * time.Millisecond})
+
+	for i := 0; i < 2; i++ {
+		res, _ := tb.Allow(ctx, "a")
+		if !res.Allowed || res.Remaining != 1-i {
+			t.Fatalf("want request %d to be allowed with %d remaining. got %+v", i, 1-i, res)
+		}
+	}
+
+	res, _ := tb.Allow(ctx, "a")
+	if res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > 50*time.Millisecond {
+		t.Fatalf("want request to be limited for up to 50ms. got %+v", res)
+	}
+
+	time.Sleep(res.RetryAfter)
+
+	if res, _ := tb.Allow(ctx, "a"); !res.Allowed {
+		t.Errorf("want a token to be refilled. got %+v", res)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := ratelimit.NewMemoryStore()
+	sw := newSlidingWindow(t, ratelimit.PerHour(3), store)
+	// limiters sharing a store share limits
+	other := newSlidingWindow(t, ratelimit.PerHour(3), store)
+
+	for i := 0; i < 3; i++ {
+		limiter := sw
+		if i%2 == 1 {
+			limiter = other
+		}
+
+		res, err := limiter.Allow(ctx, "a")
+		if err != nil || !res.Allowed {
+			t.Fatalf("want request %d to be allowed. got %+v, %v", i, res, err)
+		}
+	}
+
+	res, err := sw.Allow(ctx, "a")
+	if err != nil || res.Allowed {
+		t.Fatalf("want request to be limited. got %+v, %v", res, err)
+	}
+
+	if res.RetryAfter <= 0 || res.Remaining != 0 {
+		t.Errorf("want a retry after and no remaining requests. got %+v", res)
+	}
+
+	if res, _ := sw.Allow(ctx, "b"); !res.Allowed {
+		t.Errorf("want other callers to be allowed. got %+v", res)
+	}
+}
+
+func TestInvalidRate(t *testing.T) {
+	t.Parallel()
+
+	rates := []ratelimit.Rate{
+		{},
+		{Limit: 0, Period: time.Second},
+		{Limit: -1, Period: time.Second},
+		{Limit: 1},
+		{Limit: 1, Period: -time.Second},
+	}
+
+	for _, rate := range rates {
+		if _, err := ratelimit.NewTokenBucket(rate); err == nil {
+			t.Errorf("want an error for token bucket rate %+v", rate)
+		}
+
+		if _, err := ratelimit.NewSlidingWindow(rate, nil); err == nil {
+			t.Errorf("want an error for sliding window rate %+v", rate)
+		}
+	}
+}
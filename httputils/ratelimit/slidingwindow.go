@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Store keeps request counters for a SlidingWindow.
+// Implementing it with a shared store such as Redis (INCR and EXPIRE) shares limits between instances.
+type Store interface {
+	// Increment atomically adds 1 to the counter of key and returns the new value.
+	// A new counter expires after ttl.
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Get returns the counter of key, or 0 if it does not exist
+	Get(ctx context.Context, key string) (int64, error)
+}
+
+// SlidingWindow is a Limiter approximating a sliding window of Rate.Period with the
+// counters of the current and previous fixed windows, weighting the previous one by its overlap.
+// Rejected requests are counted, so callers exceeding the limit must back off.
+type SlidingWindow struct {
+	rate  Rate
+	store Store
+}
+
+// NewSlidingWindow returns a reference to a new SlidingWindow keeping its counters in store.
+// A nil store keeps them in memory. It returns an error if the Limit or Period of rate is not positive.
+func NewSlidingWindow(rate Rate, store Store) (*SlidingWindow, error) {
+	if err := rate.validate(); err != nil {
+		return nil, err
+	}
+
+	if store == nil {
+		store = NewMemoryStore()
+	}
+
+	return &SlidingWindow{
+		rate:  rate,
+		store: store,
+	}, nil
+}
+
+// Allow counts a request of key and reports whether it is within the limit
+func (sw *SlidingWindow) Allow(ctx context.Context, key string) (Result, error) {
+	now := time.Now()
+	window := now.UnixNano() / int64(sw.rate.Period)
+	elapsed := float64(now.UnixNano()%int64(sw.rate.Period)) / float64(sw.rate.Period)
+
+	current, err := sw.store.Increment(ctx, fmt.Sprintf("%s:%d", key, window), 2*sw.rate.Period)
+	if err != nil {
+		return Result{}, err
+	}
+
+	previous, err := sw.store.Get(ctx, fmt.Sprintf("%s:%d", key, window-1))
+	if err != nil {
+		return Result{}, err
+	}
+
+	limit := float64(sw.rate.Limit)
+	estimate := float64(previous)*(1-elapsed) + float64(current)
+
+	res := Result{
+		Allowed:   estimate <= limit,
+		Limit:     sw.rate.Limit,
+		Remaining: int(math.Max(0, limit-estimate)),
+		// the previous window no longer counts once the current one ends
+		Reset: time.Duration((1 - elapsed) * float64(sw.rate.Period)),
+	}
+
+	if !res.Allowed {
+		res.RetryAfter = sw.retryAfter(float64(previous), float64(current), elapsed)
+	}
+
+	return res, nil
+}
+
+// retryAfter estimates when the weighted count drops below the limit again
+func (sw *SlidingWindow) retryAfter(previous, current, elapsed float64) time.Duration {
+	limit := float64(sw.rate.Limit)
+	period := float64(sw.rate.Period)
+
+	if current >= limit {
+		// the current window alone is over the limit, wait until it becomes the previous window
+		// and has slid far enough: current*(1-t) < limit
+		return time.Duration((1 - elapsed + (1 - limit/current)) * period)
+	}
+
+	// previous*(1-t) + current < limit
+	t := 1 - (limit-current)/previous
+	return time.Duration((t - elapsed) * period)
+}
+
+type counter struct {
+	value   int64
+	expires time.Time
+}
+
+// MemoryStore is an in-memory Store
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+}
+
+// NewMemoryStore returns a reference to a new MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: map[string]*counter{},
+	}
+}
+
+// Increment adds 1 to the counter of key
+func (s *MemoryStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok || now.After(c.expires) {
+		if len(s.counters) >= maxBuckets {
+			s.prune(now)
+		}
+
+		c = &counter{expires: now.Add(ttl)}
+		s.counters[key] = c
+	}
+
+	c.value++
+	return c.value, nil
+}
+
+// Get returns the counter of key
+func (s *MemoryStore) Get(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok || time.Now().After(c.expires) {
+		return 0, nil
+	}
+
+	return c.value, nil
+}
+
+// prune drops expired counters. s.mu must be held.
+func (s *MemoryStore) prune(now time.Time) {
+	for key, c := range s.counters {
+		if now.After(c.expires) {
+			delete(s.counters, key)
+		}
+	}
+}
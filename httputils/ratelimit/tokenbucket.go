@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxBuckets bounds the number of callers tracked before full buckets are pruned
+const maxBuckets = 10000
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket is an in-memory Limiter allowing bursts of up to Rate.Limit requests,
+// refilled at Rate.Limit tokens per Rate.Period.
+// Limits are per instance; use a SlidingWindow with a shared Store to share them.
+type TokenBucket struct {
+	rate Rate
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewTokenBucket returns a reference to a new TokenBucket.
+// It returns an error if the Limit or Period of rate is not positive.
+func NewTokenBucket(rate Rate) (*TokenBucket, error) {
+	if err := rate.validate(); err != nil {
+		return nil, err
+	}
+
+	return &TokenBucket{
+		rate:    rate,
+		buckets: map[string]*bucket{},
+	}, nil
+}
+
+// Allow takes a token from the bucket of key, if there is one
+func (tb *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	now := time.Now()
+	capacity := float64(tb.rate.Limit)
+	perToken := tb.rate.Period / time.Duration(tb.rate.Limit)
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	b, ok := tb.buckets[key]
+	if !ok {
+		if len(tb.buckets) >= maxBuckets {
+			tb.prune(now)
+		}
+
+		b = &bucket{tokens: capacity, last: now}
+		tb.buckets[key] = b
+	}
+
+	// refill
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+
+	res := Result{
+		Allowed: b.tokens >= 1,
+		Limit:   tb.rate.Limit,
+	}
+
+	if res.Allowed {
+		b.tokens--
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((capacity - b.tokens) * float64(perToken))
+
+	return res, nil
+}
+
+// prune drops buckets that have refilled completely. tb.mu must be held.
+func (tb *TokenBucket) prune(now time.Time) {
+	for key, b := range tb.buckets {
+		if now.Sub(b.last) >= tb.rate.Period {
+			delete(tb.buckets, key)
+		}
+	}
+}
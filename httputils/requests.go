@@ -41,6 +41,12 @@ func (mr *malformedRequest) Message() string {
 }
 
 func (mr *malformedRequest) Code() string {
+	switch mr.status {
+	case http.StatusRequestEntityTooLarge:
+		return codeRequestTooLarge
+	case http.StatusUnprocessableEntity:
+		return codePatchFailed
+	}
 	return "bad_request"
 }
 
@@ -62,39 +68,17 @@ func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) err
 
 	err := dec.Decode(&dst)
 	if err != nil {
-		var syntaxError *json.SyntaxError
-		var unmarshalTypeError *json.UnmarshalTypeError
-
-		switch {
-		case errors.As(err, &syntaxError):
-			msg := fmt.Sprintf("request body contains badly-formed JSON (at position %d)", syntaxError.Offset)
-			return &malformedRequest{status: http.StatusBadRequest, msg: msg}
-
-		// https://github.com/golang/go/issues/25956.
-		case errors.Is(err, io.ErrUnexpectedEOF):
-			msg := fmt.Sprintf("request body contains badly-formed JSON")
-			return &malformedRequest{status: http.StatusBadRequest, msg: msg}
-
-		case errors.As(err, &unmarshalTypeError):
-			msg := fmt.Sprintf("request body contains an invalid value for the %q field (at position %d)", unmarshalTypeError.Field, unmarshalTypeError.Offset)
-			return &malformedRequest{status: http.StatusBadRequest, msg: msg}
-
-			// There is an open issue at https://github.com/golang/go/issues/29035 regarding
-			// turning this into a sentinel error.
-		case strings.HasPrefix(err.Error(), "json: unknown field "):
-			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
-			msg := fmt.Sprintf("request body contains unknown field %s", fieldName)
+		if msg, ok := jsonErrorMessage("request body", err); ok {
 			return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
 
+		switch {
 		case errors.Is(err, io.EOF):
 			msg := "request body must not be empty"
 			return &malformedRequest{status: http.StatusBadRequest, msg: msg}
 
-			// Again there is an open issue regarding turning this into a sentinel
-			// error at https://github.com/golang/go/issues/30715.
-		case err.Error() == "http: request body too large":
-			msg := "request body must not be larger than 1MB"
-			return &malformedRequest{status: http.StatusRequestEntityTooLarge, msg: msg}
+		case isBodyTooLarge(err):
+			return &malformedRequest{status: http.StatusRequestEntityTooLarge, msg: msgRequestTooLarge}
 
 		default:
 			return err
@@ -109,3 +93,31 @@ func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) err
 
 	return nil
 }
+
+// jsonErrorMessage describes a JSON decoding error of subject, such as "request body", to the client.
+// It returns false if err is not caused by the JSON itself.
+func jsonErrorMessage(subject string, err error) (string, bool) {
+	var syntaxError *json.SyntaxError
+	var unmarshalTypeError *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &syntaxError):
+		return fmt.Sprintf("%s contains badly-formed JSON (at position %d)", subject, syntaxError.Offset), true
+
+	// https://github.com/golang/go/issues/25956.
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Sprintf("%s contains badly-formed JSON", subject), true
+
+	case errors.As(err, &unmarshalTypeError):
+		return fmt.Sprintf("%s contains an invalid value for the %q field (at position %d)", subject, unmarshalTypeError.Field, unmarshalTypeError.Offset), true
+
+		// There is an open issue at https://github.com/golang/go/issues/29035 regarding
+		// turning this into a sentinel error.
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return fmt.Sprintf("%s contains unknown field %s", subject, fieldName), true
+
+	default:
+		return "", false
+	}
+}
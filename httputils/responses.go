@@ -1,10 +1,21 @@
 package httputils
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
-
 	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/viaduct-ai/vgo/log"
+)
+
+var (
+	contentLength = http.CanonicalHeaderKey("Content-Length")
+	// X-Request-Id is set on responses by the RequestID middleware
+	requestID = http.CanonicalHeaderKey("X-Request-Id")
 )
 
 var internalError = APIErrorResponse{
@@ -24,40 +35,216 @@ type APIError interface {
 }
 
 // APIErrorResponse is a simple API error response format.
-// In the future, we can consider a more comprehensive and standard error format,
-// such as application/problem+json
+// ProblemDetails is served instead when application/problem+json is enabled or requested.
 type APIErrorResponse struct {
 	Message string
 	Code    string
+	// Details are set by APIErrors implementing ErrorDetails
+	Details interface{} `json:",omitempty"`
+	// RequestID is the X-Request-Id of the response, set by the RequestID middleware
+	RequestID string `json:",omitempty"`
+}
+
+// ErrorDetails is implemented by APIErrors with details to serve along with their message,
+// such as the invalid fields of a request
+type ErrorDetails interface {
+	Details() interface{}
+}
+
+// ErrorHeaders is implemented by APIErrors setting response headers, such as Retry-After
+type ErrorHeaders interface {
+	Headers() http.Header
 }
 
 // ServeError serves an APIErrorResponse.
-// If the err implements the APIError interface, its content will be used in the response.
-// Else it will serve an internal error response.
+// If the err implements the APIError interface, its content will be used in the response,
+// along with its details and headers if it implements ErrorDetails and ErrorHeaders.
+// Else err is converted with the mappers registered with RegisterErrorMapper, then classified:
+//   - context.Canceled is a 499 "client_closed_request"
+//   - context.DeadlineExceeded is a 504 "timeout"
+//   - http.MaxBytesReader errors are a 413 "request_too_large"
+//   - net.Error timeouts are a 503 "unavailable"
+//
+// Other errors are served as an internal error.
+//
+// Errors are logged with the logger set with SetErrorLogger, if any: 5xx errors at the error level
+// and others, including requests cancelled by the client, at the debug level. The Error() of
+// APIErrors is logged but never served, so it can include internal information.
 func ServeError(w http.ResponseWriter, err error) {
+	serveError(w, nil, err)
+}
+
+// ServeRequestError serves err like ServeError, as application/problem+json if it is enabled with
+// SetProblemJSON or if the Accept header of r lists it. The instance of problems is the path of r.
+func ServeRequestError(w http.ResponseWriter, r *http.Request, err error) {
+	serveError(w, r, err)
+}
+
+func serveError(w http.ResponseWriter, r *http.Request, err error) {
 	var apiError APIError
-	if errors.As(err, &apiError) {
-		resp := APIErrorResponse{
+	if !errors.As(err, &apiError) {
+		apiError = mapError(err)
+	}
+
+	status := http.StatusInternalServerError
+	resp := internalError
+	var header http.Header
+
+	if apiError != nil {
+		status = apiError.Status()
+		resp = APIErrorResponse{
 			Message: apiError.Message(),
 			Code:    apiError.Code(),
 		}
 
-		ServeJSON(w, apiError.Status(), resp)
+		if d, ok := apiError.(ErrorDetails); ok {
+			resp.Details = d.Details()
+		}
+
+		if h, ok := apiError.(ErrorHeaders); ok {
+			header = h.Headers()
+		}
+	}
+
+	resp.RequestID = w.Header().Get(requestID)
+	logError(err, status, resp.Code, resp.RequestID)
+
+	if !problemJSON(r) {
+		serveJSONAs(w, status, ContentTypeJSON, resp, prettyJSON(), header)
 		return
 	}
 
-	ServeJSON(w, http.StatusInternalServerError, internalError)
+	problem := newProblem(status, resp)
+	if r != nil {
+		problem.Instance = r.URL.Path
+	}
+
+	serveJSONAs(w, status, ContentTypeProblemJSON, problem, prettyJSON(), header)
+}
+
+type errorLogger struct {
+	l log.Logger
 }
 
-// ServeJSON is serves a formatted, JSON response the user
+var errorLog atomic.Value
+
+// SetErrorLogger sets the logger of the errors served by ServeError. A nil logger disables logging.
+func SetErrorLogger(l log.Logger) {
+	errorLog.Store(errorLogger{l})
+}
+
+func logError(err error, status int, code, id string) {
+	el, _ := errorLog.Load().(errorLogger)
+	if el.l == nil || err == nil {
+		return
+	}
+
+	fields := []log.Field{
+		log.Int("status", status),
+		log.String("code", code),
+		log.Err(err),
+	}
+
+	if id != "" {
+		fields = append(fields, log.String("request_id", id))
+	}
+
+	if status >= http.StatusInternalServerError {
+		log.ErrorFields(el.l, "request failed", fields...)
+		return
+	}
+
+	log.DebugFields(el.l, "request failed", fields...)
+}
+
+// ServeJSON is serves a JSON response the user.
+// The body is compact, unless pretty-printing is enabled with SetPrettyJSON.
+// If body cannot be marshaled, an internal error response is served instead.
 func ServeJSON(w http.ResponseWriter, status int, body interface{}) {
-	resp, err := json.MarshalIndent(body, "", "\t")
+	serveJSON(w, status, body, prettyJSON())
+}
+
+func serveJSON(w http.ResponseWriter, status int, body interface{}, pretty bool) {
+	serveJSONAs(w, status, ContentTypeJSON, body, pretty, nil)
+}
 
+// serveJSONAs serves body as JSON with the given Content-Type, such as application/problem+json.
+// The headers of header, such as the ErrorHeaders of an error, are only set if body is served,
+// not on the internal error served if body cannot be marshaled.
+func serveJSONAs(w http.ResponseWriter, status int, contentType string, body interface{}, pretty bool, header http.Header) {
+	buf, err := encodeJSON(body, pretty)
 	if err != nil {
-		ServeError(w, err)
+		// internalError always marshals, so this is the only response written
+		serveJSON(w, http.StatusInternalServerError, internalError, pretty)
+		return
 	}
+	defer putBuffer(buf)
 
-	w.Header().Set("Content-Type", ContentTypeJSON)
+	h := w.Header()
+	for k, v := range header {
+		h[k] = v
+	}
+	h.Set(ContentType, contentType)
+	h.Set(contentLength, strconv.Itoa(buf.Len()))
 	w.WriteHeader(status)
-	w.Write(resp)
+	w.Write(buf.Bytes())
+}
+
+var pretty int32
+
+// SetPrettyJSON enables or disables pretty-printing of all JSON responses, for example in debug mode.
+// Serve and ServeJSONWithETag also pretty-print responses to requests with a "pretty" query parameter.
+func SetPrettyJSON(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&pretty, v)
+}
+
+func prettyJSON() bool {
+	return atomic.LoadInt32(&pretty) == 1
+}
+
+// prettyRequest returns true if the response to r should be pretty-printed
+func prettyRequest(r *http.Request) bool {
+	_, ok := r.URL.Query()["pretty"]
+	return ok || prettyJSON()
+}
+
+// maxPooledBuffer bounds the capacity of buffers returned to the pool, so a few large responses
+// do not keep memory alive
+const maxPooledBuffer = 64 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// encodeJSON encodes v into a pooled buffer, which must be returned with putBuffer
+func encodeJSON(v interface{}, pretty bool) (*bytes.Buffer, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	enc := json.NewEncoder(buf)
+	if pretty {
+		enc.SetIndent("", "\t")
+	}
+
+	if err := enc.Encode(v); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+
+	// drop the newline Encode adds, so the body is the same as json.Marshal's
+	buf.Truncate(buf.Len() - 1)
+	return buf, nil
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	bufferPool.Put(buf)
 }
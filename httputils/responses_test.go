@@ -3,12 +3,17 @@ package httputils_test
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/apierrors"
+	"github.com/viaduct-ai/vgo/testutils"
 )
 
 func TestServeJSON(t *testing.T) {
@@ -57,6 +62,7 @@ func TestServeJSON(t *testing.T) {
 	}
 
 	for _, tt := range tests {
+		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -76,7 +82,7 @@ func TestServeJSON(t *testing.T) {
 				t.Fatalf("error reading response body %v", err)
 			}
 
-			wantBody, err := json.MarshalIndent(tt.wantBody, "", "\t")
+			wantBody, err := json.Marshal(tt.wantBody)
 
 			if err != nil {
 				t.Fatalf("error marshalling tt.wantBody: %v", err)
@@ -85,6 +91,141 @@ func TestServeJSON(t *testing.T) {
 			if string(body) != string(wantBody) {
 				t.Errorf("want body %s. got %s", wantBody, body)
 			}
+
+			if got := resp.Header.Get("Content-Length"); got != strconv.Itoa(len(wantBody)) {
+				t.Errorf("want Content-Length %d. got %s", len(wantBody), got)
+			}
+		})
+	}
+}
+
+// headerCounter counts the calls to WriteHeader
+type headerCounter struct {
+	*httptest.ResponseRecorder
+	calls int
+}
+
+func (w *headerCounter) WriteHeader(status int) {
+	w.calls++
+	w.ResponseRecorder.WriteHeader(status)
+}
+
+func TestServeJSONInvalidBody(t *testing.T) {
+	t.Parallel()
+
+	w := &headerCounter{ResponseRecorder: httptest.NewRecorder()}
+	httputils.ServeJSON(w, http.StatusCreated, map[string]interface{}{"f": func() {}})
+
+	if w.calls != 1 {
+		t.Errorf("want WriteHeader to be called once. got %d", w.calls)
+	}
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("want status code %d. got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestServePretty(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		url      string
+		wantBody string
+	}{
+		{
+			name:     "Compact",
+			url:      "/",
+			wantBody: `{"test":"test"}`,
+		},
+		{
+			name:     "Pretty",
+			url:      "/?pretty",
+			wantBody: "{\n\t\"test\": \"test\"\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			httputils.Serve(rr, httptest.NewRequest(http.MethodGet, tt.url, nil), http.StatusOK, map[string]string{"test": "test"})
+
+			if got := rr.Body.String(); got != tt.wantBody {
+				t.Errorf("want body %s. got %s", tt.wantBody, got)
+			}
+		})
+	}
+}
+
+// discardWriter is a ResponseWriter that discards the response, so benchmarks only measure ServeJSON
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardWriter) WriteHeader(status int) {}
+
+type benchmarkOwner struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+var benchmarkBody = struct {
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Tags  []string       `json:"tags"`
+	Count int            `json:"count"`
+	Owner benchmarkOwner `json:"owner"`
+}{
+	ID:    "5f8d0d55b54764421b7156c9",
+	Name:  "thing",
+	Tags:  []string{"a", "b", "c"},
+	Count: 42,
+	Owner: benchmarkOwner{ID: "1234567890", Name: "John Doe"},
+}
+
+// BenchmarkServeJSON compares ServeJSON to its previous implementation, marshaling the body with the
+// same formatting then writing it
+func BenchmarkServeJSON(b *testing.B) {
+	benchmarks := []struct {
+		name    string
+		pretty  bool
+		marshal func(v interface{}) ([]byte, error)
+	}{
+		{name: "Compact", marshal: json.Marshal},
+		{name: "Pretty", pretty: true, marshal: func(v interface{}) ([]byte, error) {
+			return json.MarshalIndent(v, "", "\t")
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		w := &discardWriter{header: http.Header{}}
+
+		b.Run(bm.name, func(b *testing.B) {
+			httputils.SetPrettyJSON(bm.pretty)
+			defer httputils.SetPrettyJSON(false)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				httputils.ServeJSON(w, http.StatusOK, benchmarkBody)
+			}
+		})
+
+		b.Run(bm.name+"Marshal", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				resp, _ := bm.marshal(benchmarkBody)
+				w.Header().Set("Content-Type", httputils.ContentTypeJSON)
+				w.WriteHeader(http.StatusOK)
+				w.Write(resp)
+			}
 		})
 	}
 }
@@ -166,7 +307,7 @@ func TestServeError(t *testing.T) {
 				t.Fatalf("error reading response body %v", err)
 			}
 
-			wantBody, err := json.MarshalIndent(tt.wantBody, "", "\t")
+			wantBody, err := json.Marshal(tt.wantBody)
 
 			if err != nil {
 				t.Fatalf("error marshalling tt.wantBody: %v", err)
@@ -178,3 +319,156 @@ func TestServeError(t *testing.T) {
 		})
 	}
 }
+
+func TestServeErrorDetailsAndHeaders(t *testing.T) {
+	err := apierrors.TooManyRequests("slow down", 1500*time.Millisecond).
+		WithDetails(map[string]int{"limit": 10})
+
+	rr := httptest.NewRecorder()
+	httputils.ServeError(rr, fmt.Errorf("wrapped: %w", err))
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("want status code %d. got %d", http.StatusTooManyRequests, rr.Code)
+	}
+
+	if got := rr.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("want Retry-After 2. got %s", got)
+	}
+
+	want := `{"Message":"slow down","Code":"rate_limited","Details":{"limit":10}}`
+	if got := rr.Body.String(); got != want {
+		t.Errorf("want body %s. got %s", want, got)
+	}
+}
+
+func TestServeErrorHeadersNotMarshaled(t *testing.T) {
+	err := apierrors.TooManyRequests("slow down", time.Second).
+		WithDetails(map[string]interface{}{"f": func() {}})
+
+	rr := httptest.NewRecorder()
+	httputils.ServeError(rr, err)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("want status code %d. got %d", http.StatusInternalServerError, rr.Code)
+	}
+
+	// the headers belong to the error that could not be served
+	if got := rr.Header().Get("Retry-After"); got != "" {
+		t.Errorf("want no Retry-After. got %s", got)
+	}
+}
+
+func TestServeErrorLogging(t *testing.T) {
+	l := testutils.NewTestLogger()
+	httputils.SetErrorLogger(l)
+	defer httputils.SetErrorLogger(nil)
+
+	httputils.ServeError(httptest.NewRecorder(), apierrors.NotFound("user not found").WithInternal("user %d", 42))
+
+	if len(l.DebugLogs) != 1 || len(l.ErrorLogs) != 0 {
+		t.Fatalf("want 1 debug log. got %v debug and %v error logs", l.DebugLogs, l.ErrorLogs)
+	}
+
+	if want, got := "not_found: user not found: user 42", l.Context["error"]; got != want {
+		t.Errorf("want error %s. got %v", want, got)
+	}
+
+	httputils.ServeError(httptest.NewRecorder(), errors.New("connection refused"))
+
+	if len(l.ErrorLogs) != 1 {
+		t.Fatalf("want 1 error log. got %v", l.ErrorLogs)
+	}
+
+	if want, got := int64(500), l.Context["status"]; got != want {
+		t.Errorf("want status %d. got %v", want, got)
+	}
+}
+
+func TestServeErrorRequestID(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rr.Header().Set("X-Request-Id", "abc")
+	httputils.ServeError(rr, apierrors.NotFound("missing"))
+
+	want := `{"Message":"missing","Code":"not_found","RequestID":"abc"}`
+	if got := rr.Body.String(); got != want {
+		t.Errorf("want body %s. got %s", want, got)
+	}
+}
+
+func TestServeRequestErrorProblemJSON(t *testing.T) {
+	problem := `{"type":"about:blank","title":"Not Found","status":404,"detail":"missing","instance":"/items/1","code":"not_found","request_id":"abc"}`
+	simple := `{"Message":"missing","Code":"not_found","RequestID":"abc"}`
+
+	tests := []struct {
+		name            string
+		accept          string
+		enabled         bool
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "Accept Problem JSON",
+			accept:          "application/problem+json",
+			wantContentType: httputils.ContentTypeProblemJSON,
+			wantBody:        problem,
+		},
+		{
+			name:            "Accept Problem JSON Among Others",
+			accept:          "application/json;q=0.9, application/problem+json",
+			wantContentType: httputils.ContentTypeProblemJSON,
+			wantBody:        problem,
+		},
+		{
+			name:            "Problem JSON Refused",
+			accept:          "application/problem+json;q=0",
+			wantContentType: httputils.ContentTypeJSON,
+			wantBody:        simple,
+		},
+		{
+			name:            "Accept JSON",
+			accept:          "application/json",
+			wantContentType: httputils.ContentTypeJSON,
+			wantBody:        simple,
+		},
+		{
+			name:            "Accept Anything",
+			accept:          "*/*",
+			wantContentType: httputils.ContentTypeJSON,
+			wantBody:        simple,
+		},
+		{
+			name:            "Enabled",
+			enabled:         true,
+			wantContentType: httputils.ContentTypeProblemJSON,
+			wantBody:        problem,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httputils.SetProblemJSON(tt.enabled)
+			defer httputils.SetProblemJSON(false)
+
+			r := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			rr := httptest.NewRecorder()
+			rr.Header().Set("X-Request-Id", "abc")
+			httputils.ServeRequestError(rr, r, apierrors.NotFound("missing"))
+
+			if rr.Code != http.StatusNotFound {
+				t.Errorf("want status code %d. got %d", http.StatusNotFound, rr.Code)
+			}
+
+			if got := rr.Header().Get(httputils.ContentType); got != tt.wantContentType {
+				t.Errorf("want content type %s. got %s", tt.wantContentType, got)
+			}
+
+			if got := rr.Body.String(); got != tt.wantBody {
+				t.Errorf("want body %s. got %s", tt.wantBody, got)
+			}
+		})
+	}
+}
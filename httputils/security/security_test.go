@@ -0,0 +1,96 @@
+package security_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils/csp"
+	"github.com/viaduct-ai/vgo/httputils/security"
+)
+
+func TestConfigApply(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		cfg         *security.Config
+		wantHeaders map[string]string
+	}{
+		{
+			name: "Default",
+			cfg:  security.DefaultConfig(),
+			wantHeaders: map[string]string{
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Referrer-Policy":           "strict-origin-when-cross-origin",
+				"Permissions-Policy":        "",
+				"Content-Security-Policy":   "default-src 'none'; frame-ancestors 'none'",
+			},
+		},
+		{
+			name: "Report Only",
+			cfg: &security.Config{
+				HSTSMaxAge:        time.Hour,
+				HSTSPreload:       true,
+				PermissionsPolicy: "camera=()",
+				CSP:               csp.New().Add(csp.DefaultSrc, csp.Self).Add(csp.ReportURI, "/csp-report"),
+				CSPReportOnly:     true,
+			},
+			wantHeaders: map[string]string{
+				"Strict-Transport-Security":           "max-age=3600; preload",
+				"X-Content-Type-Options":              "",
+				"Permissions-Policy":                  "camera=()",
+				"Content-Security-Policy":             "",
+				"Content-Security-Policy-Report-Only": "default-src 'self'; report-uri /csp-report",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			if _, err := tt.cfg.Apply(rr, httptest.NewRequest(http.MethodGet, "/", nil)); err != nil {
+				t.Fatal(err)
+			}
+
+			for k, want := range tt.wantHeaders {
+				if got := rr.Header().Get(k); got != want {
+					t.Errorf("want %s %q. got %q", k, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigApplyNonce(t *testing.T) {
+	t.Parallel()
+
+	cfg := &security.Config{
+		CSP: csp.New().Add(csp.ScriptSrc, csp.Self).Nonce(csp.ScriptSrc),
+	}
+
+	rr := httptest.NewRecorder()
+	r, err := cfg.Apply(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := csp.NonceFromContext(r.Context())
+	if nonce == "" {
+		t.Fatal("want nonce in request context")
+	}
+
+	want := "script-src 'self' 'nonce-" + nonce + "'"
+	if got := rr.Header().Get("Content-Security-Policy"); got != want {
+		t.Errorf("want %q. got %q", want, got)
+	}
+
+	// every request gets its own nonce
+	r, _ = cfg.Apply(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if other := csp.NonceFromContext(r.Context()); other == "" || other == nonce {
+		t.Errorf("want a new nonce. got %q", other)
+	}
+}
@@ -0,0 +1,172 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils"
+)
+
+// NDJSON streams the values returned by next as newline-delimited JSON, until next returns io.EOF,
+// another error, or the client disconnects. Data is flushed at least every Options.FlushInterval,
+// even while next is blocked.
+//
+// A disconnect is only noticed between values, as NDJSON does not interrupt next: a next that blocks,
+// for example on a query, must return when r.Context() is done. NDJSONChannel stops on disconnect
+// even while waiting for a value.
+//
+// If next fails before returning a value, nothing is written so the caller can serve an error.
+// Later errors cannot change the 200 status already sent and truncate the stream instead.
+// It returns nil when the stream completes, and the request context error if the client disconnected.
+func NDJSON(w http.ResponseWriter, r *http.Request, next func() (interface{}, error), opts Options) error {
+	f, err := flusher(w)
+	if err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	start := time.Now()
+	count := 0
+
+	w.Header().Set(contentType, httputils.ContentTypeNDJSON)
+	w.Header().Set(accelBuffering, "no")
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	// values are encoded by this goroutine and flushed by the ticker goroutine, so data is flushed
+	// while next blocks. mu guards bw and flushErr.
+	var (
+		mu       sync.Mutex
+		flushErr error
+		wg       sync.WaitGroup
+	)
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(opts.flushInterval())
+	defer ticker.Stop()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				mu.Lock()
+				if bw.Buffered() > 0 && flushErr == nil {
+					if flushErr = bw.Flush(); flushErr == nil {
+						f.Flush()
+					}
+				}
+				mu.Unlock()
+			}
+		}
+	}()
+
+	err = func() error {
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			v, err := next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			if flushErr == nil {
+				// Encode terminates every value with a newline
+				flushErr = enc.Encode(v)
+			}
+			err = flushErr
+			mu.Unlock()
+
+			if err != nil {
+				return err
+			}
+			count++
+		}
+	}()
+
+	// the response writer must not be used once the handler returns
+	close(done)
+	wg.Wait()
+
+	// nothing is written if the stream fails before its first value, so the caller can serve an error
+	if ctx.Err() == nil && (err == nil || count > 0) {
+		bw.Flush()
+		f.Flush()
+	}
+
+	opts.logOutcome(r, "ndjson", start, count, err)
+	return err
+}
+
+// NDJSONChannel streams the values received from values as newline-delimited JSON, until the channel
+// is closed or the client disconnects, like NDJSON. Data is flushed at least every Options.FlushInterval,
+// even while no values are received.
+func NDJSONChannel(w http.ResponseWriter, r *http.Request, values <-chan interface{}, opts Options) error {
+	f, err := flusher(w)
+	if err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	start := time.Now()
+	count := 0
+
+	w.Header().Set(contentType, httputils.ContentTypeNDJSON)
+	w.Header().Set(accelBuffering, "no")
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	ticker := time.NewTicker(opts.flushInterval())
+	defer ticker.Stop()
+
+	err = func() error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+
+			case <-ticker.C:
+				if bw.Buffered() == 0 {
+					continue
+				}
+				if err := bw.Flush(); err != nil {
+					return err
+				}
+				f.Flush()
+
+			case v, ok := <-values:
+				if !ok {
+					return nil
+				}
+				if err := enc.Encode(v); err != nil {
+					return err
+				}
+				count++
+			}
+		}
+	}()
+
+	// nothing is written if the stream fails before its first value, so the caller can serve an error
+	if ctx.Err() == nil && (err == nil || count > 0) {
+		bw.Flush()
+		f.Flush()
+	}
+
+	opts.logOutcome(r, "ndjson", start, count, err)
+	return err
+}
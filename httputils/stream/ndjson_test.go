@@ -0,0 +1,220 @@
+package stream_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils"
+	"github.com/viaduct-ai/vgo/httputils/stream"
+	"github.com/viaduct-ai/vgo/testutils"
+)
+
+type item struct {
+	ID int `json:"id"`
+}
+
+// iterate returns an iterator over n items, failing with err instead of io.EOF if set
+func iterate(n int, err error) func() (interface{}, error) {
+	i := 0
+	return func() (interface{}, error) {
+		if i == n {
+			if err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		i++
+		return item{ID: i}, nil
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	t.Parallel()
+
+	errFailed := errors.New("query failed")
+
+	tests := []struct {
+		name       string
+		next       func() (interface{}, error)
+		wantErr    error
+		wantBody   string
+		wantLogs   int
+		wantErrors int
+	}{
+		{
+			name:     "Completed",
+			next:     iterate(3, nil),
+			wantBody: "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n",
+			wantLogs: 1,
+		},
+		{
+			name:     "Empty",
+			next:     iterate(0, nil),
+			wantLogs: 1,
+		},
+		{
+			name:       "Failed",
+			next:       iterate(1, errFailed),
+			wantErr:    errFailed,
+			wantBody:   "{\"id\":1}\n",
+			wantErrors: 1,
+		},
+		{
+			name:       "Failed Before First Value",
+			next:       iterate(0, errFailed),
+			wantErr:    errFailed,
+			wantErrors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			logger := testutils.NewTestLogger()
+			rr := httptest.NewRecorder()
+
+			err := stream.NDJSON(rr, httptest.NewRequest(http.MethodGet, "/export", nil), tt.next, stream.Options{Logger: logger})
+
+			if err != tt.wantErr {
+				t.Errorf("want error %v. got %v", tt.wantErr, err)
+			}
+
+			if got := rr.Body.String(); got != tt.wantBody {
+				t.Errorf("want body %q. got %q", tt.wantBody, got)
+			}
+
+			if got := rr.Header().Get("Content-Type"); got != httputils.ContentTypeNDJSON {
+				t.Errorf("want content type %s. got %s", httputils.ContentTypeNDJSON, got)
+			}
+
+			if len(logger.InfoLogs) != tt.wantLogs || len(logger.ErrorLogs) != tt.wantErrors {
+				t.Errorf("want %d info and %d error logs. got %v and %v", tt.wantLogs, tt.wantErrors, logger.InfoLogs, logger.ErrorLogs)
+			}
+		})
+	}
+}
+
+func TestNDJSONDisconnect(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/export", nil).WithContext(ctx)
+
+	i := 0
+	next := func() (interface{}, error) {
+		i++
+		if i == 2 {
+			cancel()
+		}
+		return item{ID: i}, nil
+	}
+
+	logger := testutils.NewTestLogger()
+	err := stream.NDJSON(httptest.NewRecorder(), req, next, stream.Options{Logger: logger})
+
+	if err != context.Canceled {
+		t.Errorf("want %v. got %v", context.Canceled, err)
+	}
+
+	if len(logger.InfoLogs) != 1 || logger.InfoLogs[0] != "stream stopped by client disconnect" {
+		t.Errorf("want disconnect to be logged. got %v", logger.InfoLogs)
+	}
+}
+
+// notifyingRecorder signals flushes on flushed
+type notifyingRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan struct{}
+}
+
+func (w *notifyingRecorder) Flush() {
+	w.ResponseRecorder.Flush()
+	select {
+	case w.flushed <- struct{}{}:
+	default:
+	}
+}
+
+func TestNDJSONSlowProducer(t *testing.T) {
+	t.Parallel()
+
+	rr := &notifyingRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan struct{}, 1)}
+
+	i := 0
+	next := func() (interface{}, error) {
+		i++
+		if i == 1 {
+			return item{ID: i}, nil
+		}
+
+		// the first value must be flushed while the producer blocks
+		select {
+		case <-rr.flushed:
+			return nil, io.EOF
+		case <-time.After(time.Second):
+			return nil, errors.New("value not flushed")
+		}
+	}
+
+	err := stream.NDJSON(rr, httptest.NewRequest(http.MethodGet, "/export", nil), next, stream.Options{FlushInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "{\"id\":1}\n"; rr.Body.String() != want {
+		t.Errorf("want body %q. got %q", want, rr.Body.String())
+	}
+}
+
+func TestNDJSONChannel(t *testing.T) {
+	t.Parallel()
+
+	rr := httptest.NewRecorder()
+	values := make(chan interface{})
+
+	done := make(chan error)
+	go func() {
+		done <- stream.NDJSONChannel(rr, httptest.NewRequest(http.MethodGet, "/export", nil), values, stream.Options{
+			FlushInterval: time.Millisecond,
+		})
+	}()
+
+	values <- item{ID: 1}
+	values <- item{ID: 2}
+	close(values)
+
+	if err := <-done; err != nil {
+		t.Fatalf("want no error. got %v", err)
+	}
+
+	if want := "{\"id\":1}\n{\"id\":2}\n"; rr.Body.String() != want {
+		t.Errorf("want body %q. got %q", want, rr.Body.String())
+	}
+
+	if !rr.Flushed {
+		t.Errorf("want response to be flushed")
+	}
+}
+
+func TestNDJSONChannelDisconnect(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/export", nil).WithContext(ctx)
+
+	// nothing is ever sent, the stream stops on disconnect
+	err := stream.NDJSONChannel(httptest.NewRecorder(), req, make(chan interface{}), stream.Options{})
+
+	if err != context.Canceled {
+		t.Errorf("want %v. got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,144 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ContentTypeEventStream is the content type of Server-Sent Events
+const ContentTypeEventStream = "text/event-stream"
+
+var (
+	lastEventID = http.CanonicalHeaderKey("Last-Event-ID")
+)
+
+// Event is a Server-Sent Event
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+type Event struct {
+	// ID is sent back by reconnecting clients in the Last-Event-ID header
+	ID string
+	// Event is the event type. Empty is the default "message" type.
+	Event string
+	// Data is written as is if it is a string or []byte, and as JSON otherwise
+	Data interface{}
+	// Retry sets the reconnection delay of the client, if set
+	Retry time.Duration
+}
+
+// LastEventID returns the ID of the last event a reconnecting client received, to resume the stream after it.
+// It is empty for new clients.
+func LastEventID(r *http.Request) string {
+	if id := r.Header.Get(lastEventID); id != "" {
+		return id
+	}
+
+	// polyfills of EventSource that cannot set headers send it as a query parameter
+	return r.URL.Query().Get("lastEventId")
+}
+
+// SSE streams the events received from events as Server-Sent Events, until the channel is closed or
+// the client disconnects. Events are flushed as they are sent, and comments are sent every
+// Options.HeartbeatInterval so proxies do not close idle connections.
+//
+// It returns nil when the channel is closed, and the request context error if the client disconnected.
+func SSE(w http.ResponseWriter, r *http.Request, events <-chan Event, opts Options) error {
+	f, err := flusher(w)
+	if err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	start := time.Now()
+	count := 0
+
+	h := w.Header()
+	h.Set(contentType, ContentTypeEventStream)
+	h.Set(cacheControl, "no-cache")
+	h.Set(accelBuffering, "no")
+	w.WriteHeader(http.StatusOK)
+
+	heartbeat := time.NewTicker(opts.heartbeatInterval())
+	defer heartbeat.Stop()
+
+	err = func() error {
+		if opts.Retry > 0 {
+			if _, err := fmt.Fprintf(w, "retry: %d\n\n", opts.Retry.Milliseconds()); err != nil {
+				return err
+			}
+		}
+		f.Flush()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+
+			case <-heartbeat.C:
+				if _, err := w.Write([]byte(": heartbeat\n\n")); err != nil {
+					return err
+				}
+				f.Flush()
+
+			case e, ok := <-events:
+				if !ok {
+					return nil
+				}
+
+				b, err := encodeEvent(e)
+				if err != nil {
+					return err
+				}
+				if _, err := w.Write(b); err != nil {
+					return err
+				}
+				f.Flush()
+				count++
+			}
+		}
+	}()
+
+	opts.logOutcome(r, "sse", start, count, err)
+	return err
+}
+
+// encodeEvent encodes e in the event stream format
+func encodeEvent(e Event) ([]byte, error) {
+	var data string
+	switch d := e.Data.(type) {
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		data = string(b)
+	}
+
+	var buf bytes.Buffer
+
+	// fields cannot contain newlines, they would start a new field
+	if e.ID != "" {
+		buf.WriteString("id: " + strings.NewReplacer("\r", "", "\n", "").Replace(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		buf.WriteString("event: " + strings.NewReplacer("\r", "", "\n", "").Replace(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&buf, "retry: %d\n", e.Retry.Milliseconds())
+	}
+
+	// multiline data is sent as one data field per line
+	for _, line := range strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(data), "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,104 @@
+package stream_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/httputils/stream"
+)
+
+func TestSSE(t *testing.T) {
+	t.Parallel()
+
+	events := make(chan stream.Event, 3)
+	events <- stream.Event{ID: "1", Event: "progress", Data: map[string]int{"percent": 50}}
+	events <- stream.Event{ID: "2", Data: "line 1\nline 2", Retry: 5 * time.Second}
+	events <- stream.Event{Data: []byte("bytes")}
+	close(events)
+
+	rr := httptest.NewRecorder()
+	err := stream.SSE(rr, httptest.NewRequest(http.MethodGet, "/progress", nil), events, stream.Options{Retry: time.Second})
+
+	if err != nil {
+		t.Fatalf("want no error. got %v", err)
+	}
+
+	want := "retry: 1000\n\n" +
+		"id: 1\nevent: progress\ndata: {\"percent\":50}\n\n" +
+		"id: 2\nretry: 5000\ndata: line 1\ndata: line 2\n\n" +
+		"data: bytes\n\n"
+
+	if got := rr.Body.String(); got != want {
+		t.Errorf("want body %q. got %q", want, got)
+	}
+
+	wantHeaders := map[string]string{
+		"Content-Type":  stream.ContentTypeEventStream,
+		"Cache-Control": "no-cache",
+	}
+	for k, want := range wantHeaders {
+		if got := rr.Header().Get(k); got != want {
+			t.Errorf("want %s %s. got %s", k, want, got)
+		}
+	}
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/progress", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	err := stream.SSE(rr, req, make(chan stream.Event), stream.Options{HeartbeatInterval: 10 * time.Millisecond})
+
+	if err != context.DeadlineExceeded {
+		t.Errorf("want %v. got %v", context.DeadlineExceeded, err)
+	}
+
+	if !strings.Contains(rr.Body.String(), ": heartbeat\n\n") {
+		t.Errorf("want heartbeats. got %q", rr.Body.String())
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	t.Parallel()
+
+	header := httptest.NewRequest(http.MethodGet, "/progress", nil)
+	header.Header.Set("Last-Event-ID", "41")
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want string
+	}{
+		{
+			name: "New",
+			req:  httptest.NewRequest(http.MethodGet, "/progress", nil),
+		},
+		{
+			name: "Header",
+			req:  header,
+			want: "41",
+		},
+		{
+			name: "Query",
+			req:  httptest.NewRequest(http.MethodGet, "/progress?lastEventId=42", nil),
+			want: "42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stream.LastEventID(tt.req); got != tt.want {
+				t.Errorf("want %q. got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Package stream writes streaming HTTP responses: newline-delimited JSON and Server-Sent Events.
+//
+// Streams stop when their source is exhausted or the client disconnects, which is detected
+// through the request context, and their outcome is logged.
+package stream
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/viaduct-ai/vgo/log"
+)
+
+var (
+	cacheControl = http.CanonicalHeaderKey("Cache-Control")
+	contentType  = http.CanonicalHeaderKey("Content-Type")
+	// disables response buffering of proxies such as nginx
+	accelBuffering = http.CanonicalHeaderKey("X-Accel-Buffering")
+)
+
+// ErrFlushNotSupported is returned when the response writer cannot flush, so the response cannot be streamed
+var ErrFlushNotSupported = errors.New("stream: http.ResponseWriter does not implement http.Flusher")
+
+// Options configures a stream
+type Options struct {
+	// FlushInterval is the maximum time written data is buffered before it is flushed to the client.
+	// Defaults to 1s for NDJSON. Server-Sent Events are flushed as they are sent.
+	FlushInterval time.Duration
+	// HeartbeatInterval is the interval of Server-Sent Events comments sent to keep idle connections open.
+	// Defaults to 15s.
+	HeartbeatInterval time.Duration
+	// Retry is sent to Server-Sent Events clients as the reconnection delay, if set
+	Retry time.Duration
+	// Logger logs the outcome of the stream, if set
+	Logger log.Logger
+}
+
+func (o *Options) flushInterval() time.Duration {
+	if o.FlushInterval <= 0 {
+		return time.Second
+	}
+	return o.FlushInterval
+}
+
+func (o *Options) heartbeatInterval() time.Duration {
+	if o.HeartbeatInterval <= 0 {
+		return 15 * time.Second
+	}
+	return o.HeartbeatInterval
+}
+
+// logOutcome logs how a stream of r ended
+func (o *Options) logOutcome(r *http.Request, kind string, start time.Time, count int, err error) {
+	if o.Logger == nil {
+		return
+	}
+
+	fields := []log.Field{
+		log.String("stream", kind),
+		log.String("endpoint", r.URL.Path),
+		log.Int("count", count),
+		log.Duration("duration", time.Since(start)),
+	}
+
+	switch {
+	case err == nil:
+		log.InfoFields(o.Logger, "stream completed", fields...)
+	case r.Context().Err() != nil:
+		log.InfoFields(o.Logger, "stream stopped by client disconnect", fields...)
+	default:
+		log.ErrorFields(o.Logger, "stream failed", append(fields, log.Err(err))...)
+	}
+}
+
+func flusher(w http.ResponseWriter) (http.Flusher, error) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrFlushNotSupported
+	}
+	return f, nil
+}
@@ -0,0 +1,262 @@
+// Package errgen generates httputils.APIError types, documentation and an OpenAPI components section
+// from declarative error catalogs. It is the implementation of the errgen command.
+package errgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	codePattern  = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	paramPattern = regexp.MustCompile(`^[a-z][a-zA-Z0-9_]*$`)
+	// placeholders are the {param} of message templates
+	placeholders = regexp.MustCompile(`\{([^{}]*)\}`)
+)
+
+// methods of the generated types, which params must not be named after
+var methods = map[string]bool{"Error": true, "Status": true, "Message": true, "Code": true}
+
+// goTypes are the Go types of params by their catalog type
+var goTypes = map[string]string{
+	"string":  "string",
+	"int":     "int",
+	"int64":   "int64",
+	"float":   "float64",
+	"float64": "float64",
+	"bool":    "bool",
+}
+
+// File is an error catalog file
+type File struct {
+	Errors []Error `json:"errors" yaml:"errors"`
+}
+
+// Error is an error of a catalog
+type Error struct {
+	// Code is the error code, such as "user_not_found"
+	Code string `json:"code" yaml:"code"`
+	// Status is the HTTP status of the error
+	Status int `json:"status" yaml:"status"`
+	// Message is the template of the message served, with {param} placeholders
+	Message string `json:"message" yaml:"message"`
+	// Description documents when the error is returned
+	Description string `json:"description" yaml:"description"`
+	// Type is the name of the generated Go type. Defaults to the camel-cased code followed by "Error".
+	Type   string  `json:"type" yaml:"type"`
+	Params []Param `json:"params" yaml:"params"`
+
+	// source is the file the error is defined in
+	source string
+}
+
+// Param is a typed parameter of an error message
+type Param struct {
+	Name string `json:"name" yaml:"name"`
+	// Type is one of string, int, int64, float, float64 and bool. Defaults to string.
+	Type string `json:"type" yaml:"type"`
+	// Description documents the parameter
+	Description string `json:"description" yaml:"description"`
+}
+
+// Load parses and validates catalog files, YAML or JSON depending on their extension.
+// It returns the errors of all the files, in order.
+func Load(paths ...string) ([]Error, error) {
+	var all []Error
+
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		errs, err := Parse(path, data)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, errs...)
+	}
+
+	if err := Validate(all); err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+// Parse parses a catalog file named name. Files with a .json extension are parsed as JSON,
+// others as YAML. Unknown fields are rejected.
+func Parse(name string, data []byte) ([]Error, error) {
+	var f File
+
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&f); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	} else if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	for i := range f.Errors {
+		f.Errors[i].source = name
+
+		for j := range f.Errors[i].Params {
+			if f.Errors[i].Params[j].Type == "" {
+				f.Errors[i].Params[j].Type = "string"
+			}
+		}
+	}
+
+	return f.Errors, nil
+}
+
+// Validate checks the errors of one or more catalogs. It reports duplicate codes and Go types,
+// conflicting statuses of a code, and invalid codes, statuses, params and message templates.
+func Validate(errs []Error) error {
+	var problems []string
+	report := func(e *Error, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s: %s: %s", e.source, e.Code, fmt.Sprintf(format, args...)))
+	}
+
+	codes := map[string]*Error{}
+	types := map[string]*Error{}
+
+	for i := range errs {
+		e := &errs[i]
+
+		if !codePattern.MatchString(e.Code) {
+			report(e, "code must be snake_case")
+		}
+
+		if e.Status < 400 || e.Status > 599 {
+			report(e, "status %d is not a 4xx or 5xx status", e.Status)
+		}
+
+		if e.Message == "" {
+			report(e, "message is required")
+		}
+
+		if prev, ok := codes[e.Code]; ok {
+			if prev.Status != e.Status {
+				report(e, "conflicting status %d, %s defines it with status %d", e.Status, prev.source, prev.Status)
+			} else {
+				report(e, "duplicate code, already defined in %s", prev.source)
+			}
+		} else {
+			codes[e.Code] = e
+		}
+
+		if e.Type != "" && (!token.IsIdentifier(e.Type) || !token.IsExported(e.Type)) {
+			report(e, "type %q is not an exported Go identifier", e.Type)
+		}
+
+		if prev, ok := types[e.TypeName()]; ok && prev.Code != e.Code {
+			report(e, "duplicate type %s, already generated for %s in %s", e.TypeName(), prev.Code, prev.source)
+		} else {
+			types[e.TypeName()] = e
+		}
+
+		params := map[string]bool{}
+		for _, p := range e.Params {
+			if !paramPattern.MatchString(p.Name) {
+				report(e, "param %q must be camelCase or snake_case", p.Name)
+			}
+			if methods[exported(p.Name)] {
+				report(e, "param %q clashes with the %s method", p.Name, exported(p.Name))
+			}
+			if params[p.Name] {
+				report(e, "duplicate param %q", p.Name)
+			}
+			if _, ok := goTypes[p.Type]; !ok {
+				report(e, "param %q has unknown type %q", p.Name, p.Type)
+			}
+			params[p.Name] = true
+		}
+
+		for _, m := range placeholders.FindAllStringSubmatch(e.Message, -1) {
+			if !params[m[1]] {
+				report(e, "message references undefined param %q", m[1])
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid error catalog:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+
+	return nil
+}
+
+// TypeName returns the name of the Go type of e
+func (e *Error) TypeName() string {
+	if e.Type != "" {
+		return e.Type
+	}
+	return exported(e.Code) + "Error"
+}
+
+// initialisms are upper-cased in Go identifiers
+var initialisms = map[string]bool{
+	"api": true, "id": true, "ip": true, "json": true, "http": true,
+	"sql": true, "uri": true, "url": true, "uuid": true, "jwt": true,
+}
+
+// exported converts a snake_case or camelCase name to an exported Go identifier
+func exported(name string) string {
+	var b strings.Builder
+	for _, w := range words(name) {
+		b.WriteString(title(w))
+	}
+	return b.String()
+}
+
+// unexported converts a snake_case or camelCase name to an unexported Go identifier
+func unexported(name string) string {
+	var b strings.Builder
+	for i, w := range words(name) {
+		if i == 0 {
+			b.WriteString(strings.ToLower(w))
+			continue
+		}
+		b.WriteString(title(w))
+	}
+	return b.String()
+}
+
+// title upper-cases the first letter of w, or all of it if it is an initialism
+func title(w string) string {
+	if initialisms[strings.ToLower(w)] {
+		return strings.ToUpper(w)
+	}
+	return strings.ToUpper(w[:1]) + w[1:]
+}
+
+// words splits a snake_case or camelCase name into its words
+func words(name string) []string {
+	var words []string
+
+	for _, part := range strings.Split(name, "_") {
+		start := 0
+		for i, r := range part {
+			if i > 0 && r >= 'A' && r <= 'Z' {
+				words = append(words, part[start:i])
+				start = i
+			}
+		}
+		if start < len(part) {
+			words = append(words, part[start:])
+		}
+	}
+
+	return words
+}
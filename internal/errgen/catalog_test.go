@@ -0,0 +1,205 @@
+package errgen_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/viaduct-ai/vgo/internal/errgen"
+)
+
+const usersCatalog = `
+errors:
+  - code: user_not_found
+    status: 404
+    message: user {id} not found
+    params:
+      - name: id
+  - code: quota_exceeded
+    status: 429
+    message: quota exceeded
+`
+
+func writeCatalogs(t *testing.T, files map[string]string) (string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "errgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestLoad(t *testing.T) {
+	dir, cleanup := writeCatalogs(t, map[string]string{
+		"users.yaml":    usersCatalog,
+		"payments.json": `{"errors": [{"code": "payment_required", "status": 402, "message": "payment required"}]}`,
+	})
+	defer cleanup()
+
+	errs, err := errgen.Load(filepath.Join(dir, "users.yaml"), filepath.Join(dir, "payments.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var codes []string
+	for _, e := range errs {
+		codes = append(codes, e.Code)
+	}
+
+	want := "user_not_found,quota_exceeded,payment_required"
+	if got := strings.Join(codes, ","); got != want {
+		t.Errorf("want codes %s. got %s", want, got)
+	}
+
+	if got := errs[0].Params[0].Type; got != "string" {
+		t.Errorf("want default param type string. got %s", got)
+	}
+}
+
+func TestLoadConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		other   string
+		wantErr string
+	}{
+		{
+			name: "duplicate code",
+			other: `
+errors:
+  - code: user_not_found
+    status: 404
+    message: no such user
+`,
+			wantErr: "other.yaml: user_not_found: duplicate code, already defined in",
+		},
+		{
+			name: "conflicting status",
+			other: `
+errors:
+  - code: user_not_found
+    status: 410
+    message: user is gone
+`,
+			wantErr: "other.yaml: user_not_found: conflicting status 410",
+		},
+		{
+			name: "duplicate type",
+			other: `
+errors:
+  - code: user_gone
+    status: 410
+    message: user is gone
+    type: UserNotFoundError
+`,
+			wantErr: "other.yaml: user_gone: duplicate type UserNotFoundError, already generated for user_not_found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, cleanup := writeCatalogs(t, map[string]string{
+				"users.yaml": usersCatalog,
+				"other.yaml": tt.other,
+			})
+			defer cleanup()
+
+			_, err := errgen.Load(filepath.Join(dir, "users.yaml"), filepath.Join(dir, "other.yaml"))
+			if err == nil {
+				t.Fatal("want error. got nil")
+			}
+
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("want error containing %q. got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		catalog string
+		wantErr string
+	}{
+		{
+			name:    "invalid code",
+			catalog: "errors: [{code: UserNotFound, status: 404, message: not found}]",
+			wantErr: "code must be snake_case",
+		},
+		{
+			name:    "invalid status",
+			catalog: "errors: [{code: not_found, status: 200, message: not found}]",
+			wantErr: "status 200 is not a 4xx or 5xx status",
+		},
+		{
+			name:    "missing message",
+			catalog: "errors: [{code: not_found, status: 404}]",
+			wantErr: "message is required",
+		},
+		{
+			name:    "undefined param",
+			catalog: "errors: [{code: not_found, status: 404, message: '{id} not found'}]",
+			wantErr: `message references undefined param "id"`,
+		},
+		{
+			name:    "unknown param type",
+			catalog: "errors: [{code: not_found, status: 404, message: not found, params: [{name: id, type: uuid}]}]",
+			wantErr: `param "id" has unknown type "uuid"`,
+		},
+		{
+			name:    "duplicate param",
+			catalog: "errors: [{code: not_found, status: 404, message: not found, params: [{name: id}, {name: id}]}]",
+			wantErr: `duplicate param "id"`,
+		},
+		{
+			name:    "unexported type",
+			catalog: "errors: [{code: not_found, status: 404, message: not found, type: notFound}]",
+			wantErr: `type "notFound" is not an exported Go identifier`,
+		},
+		{
+			name:    "invalid type",
+			catalog: "errors: [{code: not_found, status: 404, message: not found, type: Not-Found}]",
+			wantErr: `type "Not-Found" is not an exported Go identifier`,
+		},
+		{
+			name:    "param clashing with method",
+			catalog: "errors: [{code: not_found, status: 404, message: not found, params: [{name: code}]}]",
+			wantErr: `param "code" clashes with the Code method`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := errgen.Parse("errors.yaml", []byte(tt.catalog))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = errgen.Validate(errs)
+			if err == nil {
+				t.Fatal("want error. got nil")
+			}
+
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("want error containing %q. got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseUnknownField(t *testing.T) {
+	_, err := errgen.Parse("errors.yaml", []byte("errors: [{code: not_found, status: 404, mesage: typo}]"))
+	if err == nil {
+		t.Errorf("want error. got nil")
+	}
+}
@@ -0,0 +1,114 @@
+package errgen
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// GenerateDocs returns the Markdown documentation of errs
+func GenerateDocs(errs []Error) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("<!-- Code generated by errgen. DO NOT EDIT. -->\n\n")
+	b.WriteString("# Errors\n\n")
+	b.WriteString("Errors are served as `{\"Message\": \"...\", \"Code\": \"...\"}`.\n\n")
+	b.WriteString("| Code | Status | Message |\n")
+	b.WriteString("| --- | --- | --- |\n")
+
+	for _, e := range errs {
+		fmt.Fprintf(&b, "| [`%s`](#%s) | %d %s | %s |\n", e.Code, e.Code, e.Status, http.StatusText(e.Status), markdownCell(e.Message))
+	}
+
+	for _, e := range errs {
+		fmt.Fprintf(&b, "\n## %s\n\n", e.Code)
+		fmt.Fprintf(&b, "%d %s: %s\n", e.Status, http.StatusText(e.Status), e.Message)
+
+		if d := strings.TrimSpace(e.Description); d != "" {
+			fmt.Fprintf(&b, "\n%s\n", d)
+		}
+
+		if len(e.Params) == 0 {
+			continue
+		}
+
+		b.WriteString("\n| Param | Type | Description |\n")
+		b.WriteString("| --- | --- | --- |\n")
+		for _, p := range e.Params {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", p.Name, p.Type, markdownCell(p.Description))
+		}
+	}
+
+	return b.Bytes()
+}
+
+// markdownCell escapes s for a table cell
+func markdownCell(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(strings.TrimSpace(s), "\n", " ", -1)
+}
+
+// GenerateOpenAPI returns an OpenAPI 3 components section with the APIError schema,
+// with the codes of errs, and a response per error to reference from operations,
+// such as $ref: '#/components/responses/UserNotFound'.
+func GenerateOpenAPI(errs []Error) ([]byte, error) {
+	codes := make([]string, len(errs))
+	for i, e := range errs {
+		codes[i] = e.Code
+	}
+
+	schema := yaml.MapSlice{
+		{Key: "type", Value: "object"},
+		{Key: "required", Value: []string{"Message", "Code"}},
+		{Key: "properties", Value: yaml.MapSlice{
+			{Key: "Message", Value: yaml.MapSlice{{Key: "type", Value: "string"}}},
+			{Key: "Code", Value: yaml.MapSlice{
+				{Key: "type", Value: "string"},
+				{Key: "enum", Value: codes},
+			}},
+			{Key: "Details", Value: yaml.MapSlice{}},
+			{Key: "RequestID", Value: yaml.MapSlice{{Key: "type", Value: "string"}}},
+		}},
+	}
+
+	var responses yaml.MapSlice
+	for _, e := range errs {
+		description := e.Message
+		if d := strings.TrimSpace(e.Description); d != "" {
+			description = d
+		}
+
+		responses = append(responses, yaml.MapItem{
+			Key: strings.TrimSuffix(e.TypeName(), "Error"),
+			Value: yaml.MapSlice{
+				{Key: "description", Value: fmt.Sprintf("%d %s: %s", e.Status, e.Code, description)},
+				{Key: "content", Value: yaml.MapSlice{
+					{Key: "application/json", Value: yaml.MapSlice{
+						{Key: "schema", Value: yaml.MapSlice{{Key: "$ref", Value: "#/components/schemas/APIError"}}},
+						{Key: "example", Value: yaml.MapSlice{
+							{Key: "Message", Value: e.Message},
+							{Key: "Code", Value: e.Code},
+						}},
+					}},
+				}},
+			},
+		})
+	}
+
+	doc := yaml.MapSlice{
+		{Key: "components", Value: yaml.MapSlice{
+			{Key: "schemas", Value: yaml.MapSlice{{Key: "APIError", Value: schema}}},
+			{Key: "responses", Value: responses},
+		}},
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte("# Code generated by errgen. DO NOT EDIT.\n"), out...), nil
+}
@@ -0,0 +1,172 @@
+package errgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// header marks generated files, see https://golang.org/s/generatedcode
+const header = "// Code generated by errgen. DO NOT EDIT."
+
+// field is a param of a generated type
+type field struct {
+	Name string
+	// Arg is the name of the constructor argument of the field
+	Arg         string
+	Type        string
+	Description string
+}
+
+// fields returns the struct fields of the params of e
+func (e *Error) fields() []field {
+	fields := make([]field, len(e.Params))
+	for i, p := range e.Params {
+		arg := unexported(p.Name)
+		if token.Lookup(arg).IsKeyword() {
+			arg += "Value"
+		}
+
+		fields[i] = field{
+			Name:        exported(p.Name),
+			Arg:         arg,
+			Type:        goTypes[p.Type],
+			Description: p.Description,
+		}
+	}
+	return fields
+}
+
+// sprintf returns the fmt.Sprintf call building the message of e from its fields,
+// or the quoted message if it has no placeholders
+func (e *Error) sprintf(recv string) string {
+	var args []string
+
+	format := placeholders.ReplaceAllStringFunc(strings.Replace(e.Message, "%", "%%", -1), func(m string) string {
+		args = append(args, recv+"."+exported(m[1:len(m)-1]))
+		return "%v"
+	})
+
+	if len(args) == 0 {
+		return fmt.Sprintf("%q", e.Message)
+	}
+
+	return fmt.Sprintf("fmt.Sprintf(%q, %s)", format, strings.Join(args, ", "))
+}
+
+// comment continues the comment text with a "//" line for every further line of text, indented with indent
+func comment(indent, text string) string {
+	lines := strings.Split(strings.Replace(strings.TrimSpace(text), "\r\n", "\n", -1), "\n")
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.TrimRight(indent+"// "+strings.TrimSpace(lines[i]), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+var goTemplate = template.Must(template.New("go").Funcs(template.FuncMap{"comment": comment}).Parse(`{{.Header}}
+// Source: {{.Sources}}
+
+package {{.Package}}
+
+{{if .UsesFmt}}import "fmt"
+{{end}}
+{{range .Errors}}
+// {{.Type}} is the {{.Status}} {{.StatusText}} error with code "{{.Code}}"{{if .Description}}.
+// {{comment "" .Description}}{{end}}
+type {{.Type}} struct{{if .Fields}} {
+{{- range .Fields}}
+	{{if .Description}}// {{.Name}} is {{comment "\t" .Description}}
+	{{end}}{{.Name}} {{.Type}}
+{{- end}}
+}{{else}}{}{{end}}
+
+// New{{.Type}} returns a reference to a new {{.Type}}
+func New{{.Type}}({{range $i, $f := .Fields}}{{if $i}}, {{end}}{{$f.Arg}} {{$f.Type}}{{end}}) *{{.Type}} {
+	return &{{.Type}}{
+{{- range .Fields}}
+		{{.Name}}: {{.Arg}},
+{{- end}}
+	}
+}
+
+func (e *{{.Type}}) Error() string {
+	return "{{.Code}}: " + e.Message()
+}
+
+// Status is {{.Status}} {{.StatusText}}
+func (e *{{.Type}}) Status() int {
+	return {{.Status}}
+}
+
+// Message returns the message served to clients
+func (e *{{.Type}}) Message() string {
+	return {{.Sprintf}}
+}
+
+// Code is "{{.Code}}"
+func (e *{{.Type}}) Code() string {
+	return "{{.Code}}"
+}
+{{end}}`))
+
+type goError struct {
+	Type        string
+	Code        string
+	Status      int
+	StatusText  string
+	Description string
+	Fields      []field
+	Sprintf     string
+}
+
+// GenerateGo returns the source of the Go types of errs, in package pkg.
+// sources are the catalog files, named in the header of the source.
+func GenerateGo(pkg string, sources []string, errs []Error) ([]byte, error) {
+	data := struct {
+		Header  string
+		Sources string
+		Package string
+		UsesFmt bool
+		Errors  []goError
+	}{
+		Header:  header,
+		Sources: strings.Join(sources, ", "),
+		Package: pkg,
+	}
+
+	for i := range errs {
+		e := &errs[i]
+
+		ge := goError{
+			Type:        e.TypeName(),
+			Code:        e.Code,
+			Status:      e.Status,
+			StatusText:  http.StatusText(e.Status),
+			Description: strings.TrimSpace(e.Description),
+			Fields:      e.fields(),
+			Sprintf:     e.sprintf("e"),
+		}
+
+		if strings.HasPrefix(ge.Sprintf, "fmt.") {
+			data.UsesFmt = true
+		}
+
+		data.Errors = append(data.Errors, ge)
+	}
+
+	var buf bytes.Buffer
+	if err := goTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated source: %w", err)
+	}
+
+	return src, nil
+}
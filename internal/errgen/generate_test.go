@@ -0,0 +1,190 @@
+package errgen_test
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/viaduct-ai/vgo/internal/errgen"
+)
+
+const catalog = `
+errors:
+  - code: user_not_found
+    status: 404
+    message: user {user_id} not found, 100% sure
+    description: The user does not exist.
+    params:
+      - name: user_id
+        description: the ID of the user
+      - name: type
+        type: int
+  - code: payment_required
+    status: 402
+    message: payment required
+`
+
+func parseCatalog(t *testing.T) []errgen.Error {
+	t.Helper()
+
+	errs, err := errgen.Parse("errors.yaml", []byte(catalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := errgen.Validate(errs); err != nil {
+		t.Fatal(err)
+	}
+
+	return errs
+}
+
+func TestGenerateGo(t *testing.T) {
+	src, err := errgen.GenerateGo("errs", []string{"errors.yaml"}, parseCatalog(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), "errors_gen.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("generated source does not parse: %v\n%s", err, src)
+	}
+
+	if f.Name.Name != "errs" {
+		t.Errorf("want package errs. got %s", f.Name.Name)
+	}
+
+	if !strings.HasPrefix(string(src), "// Code generated by errgen. DO NOT EDIT.\n") {
+		t.Errorf("want generated file header. got\n%s", src)
+	}
+
+	for _, want := range []string{
+		"type UserNotFoundError struct {",
+		"\t// UserID is the ID of the user\n\tUserID string",
+		"func NewUserNotFoundError(userID string, typeValue int) *UserNotFoundError {",
+		`return fmt.Sprintf("user %v not found, 100%% sure", e.UserID)`,
+		"func (e *UserNotFoundError) Status() int {\n\treturn 404\n}",
+		"func (e *UserNotFoundError) Code() string {\n\treturn \"user_not_found\"\n}",
+		"type PaymentRequiredError struct{}",
+		"return \"payment required\"",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("want source containing %q. got\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateGoWithoutParams(t *testing.T) {
+	errs, err := errgen.Parse("errors.yaml", []byte("errors: [{code: gone, status: 410, message: gone}]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := errgen.GenerateGo("errs", []string{"errors.yaml"}, errs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(src), `import "fmt"`) {
+		t.Errorf("want no fmt import. got\n%s", src)
+	}
+}
+
+func TestGenerateGoMultilineDescriptions(t *testing.T) {
+	errs, err := errgen.Parse("errors.yaml", []byte(`
+errors:
+  - code: user_not_found
+    status: 404
+    message: user {id} not found
+    description: |
+      The user does not exist.
+
+      It may have been deleted.
+    params:
+      - name: id
+        description: |
+          the ID
+          of the user
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := errgen.GenerateGo("errs", []string{"errors.yaml"}, errs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "errors_gen.go", src, parser.ParseComments); err != nil {
+		t.Fatalf("generated source does not parse: %v\n%s", err, src)
+	}
+
+	for _, want := range []string{
+		"// The user does not exist.\n//\n// It may have been deleted.\ntype UserNotFoundError struct {",
+		"\t// ID is the ID\n\t// of the user\n\tID string",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("want source containing %q. got\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateDocs(t *testing.T) {
+	docs := string(errgen.GenerateDocs(parseCatalog(t)))
+
+	for _, want := range []string{
+		"| [`user_not_found`](#user_not_found) | 404 Not Found | user {user_id} not found, 100% sure |",
+		"## payment_required\n\n402 Payment Required: payment required",
+		"The user does not exist.",
+		"| `type` | int |  |",
+	} {
+		if !strings.Contains(docs, want) {
+			t.Errorf("want docs containing %q. got\n%s", want, docs)
+		}
+	}
+}
+
+func TestGenerateOpenAPI(t *testing.T) {
+	out, err := errgen.GenerateOpenAPI(parseCatalog(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Components struct {
+			Schemas struct {
+				APIError struct {
+					Properties struct {
+						Code struct {
+							Enum []string `yaml:"enum"`
+						} `yaml:"Code"`
+					} `yaml:"properties"`
+				} `yaml:"APIError"`
+			} `yaml:"schemas"`
+			Responses map[string]struct {
+				Description string `yaml:"description"`
+			} `yaml:"responses"`
+		} `yaml:"components"`
+	}
+
+	if err := yaml.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	enum := strings.Join(doc.Components.Schemas.APIError.Properties.Code.Enum, ",")
+	if want := "user_not_found,payment_required"; enum != want {
+		t.Errorf("want code enum %s. got %s", want, enum)
+	}
+
+	want := "404 user_not_found: The user does not exist."
+	if got := doc.Components.Responses["UserNotFound"].Description; got != want {
+		t.Errorf("want description %s. got %s", want, got)
+	}
+
+	if _, ok := doc.Components.Responses["PaymentRequired"]; !ok {
+		t.Errorf("want PaymentRequired response. got %v", doc.Components.Responses)
+	}
+}
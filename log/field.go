@@ -0,0 +1,174 @@
+package log
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Field is a strongly typed key-value pair of log context.
+// Fields are passed to zap loggers as is, avoiding the reflection and
+// allocations of loosely typed key-value pairs.
+type Field = zapcore.Field
+
+// ObjectMarshaler is implemented by types that log themselves as a nested object without reflection
+type ObjectMarshaler = zapcore.ObjectMarshaler
+
+// ObjectMarshalerFunc adapts a function to an ObjectMarshaler
+type ObjectMarshalerFunc = zapcore.ObjectMarshalerFunc
+
+// ObjectEncoder is the encoder passed to an ObjectMarshaler
+type ObjectEncoder = zapcore.ObjectEncoder
+
+// FieldLogger is implemented by Loggers that accept typed Fields.
+// Use the package level DebugFields, InfoFields, ErrorFields and WithFields functions
+// to log Fields with any Logger.
+type FieldLogger interface {
+	Logger
+	DebugFields(msg string, fields ...Field)
+	InfoFields(msg string, fields ...Field)
+	ErrorFields(msg string, fields ...Field)
+	WithFields(fields ...Field) Logger
+}
+
+// String constructs a Field with a string value
+func String(key string, val string) Field {
+	return zap.String(key, val)
+}
+
+// Int constructs a Field with an int value
+func Int(key string, val int) Field {
+	return zap.Int(key, val)
+}
+
+// Int64 constructs a Field with an int64 value
+func Int64(key string, val int64) Field {
+	return zap.Int64(key, val)
+}
+
+// Float64 constructs a Field with a float64 value
+func Float64(key string, val float64) Field {
+	return zap.Float64(key, val)
+}
+
+// Bool constructs a Field with a bool value
+func Bool(key string, val bool) Field {
+	return zap.Bool(key, val)
+}
+
+// Err constructs a Field with the error under the "error" key
+func Err(err error) Field {
+	return zap.Error(err)
+}
+
+// Duration constructs a Field with a time.Duration value
+func Duration(key string, val time.Duration) Field {
+	return zap.Duration(key, val)
+}
+
+// Time constructs a Field with a time.Time value
+func Time(key string, val time.Time) Field {
+	return zap.Time(key, val)
+}
+
+// Strings constructs a Field with a slice of strings
+func Strings(key string, val []string) Field {
+	return zap.Strings(key, val)
+}
+
+// Object constructs a Field with a nested object
+func Object(key string, val ObjectMarshaler) Field {
+	return zap.Object(key, val)
+}
+
+// Any constructs a Field with an arbitrary value.
+// Known types use their typed constructor, others fall back to reflection.
+func Any(key string, val interface{}) Field {
+	return zap.Any(key, val)
+}
+
+// DebugFields logs msg with fields at the debug level
+func DebugFields(l Logger, msg string, fields ...Field) {
+	if fl, ok := l.(FieldLogger); ok {
+		fl.DebugFields(msg, fields...)
+		return
+	}
+
+	l.With(keysAndValues(fields)...).Debug(msg)
+}
+
+// InfoFields logs msg with fields at the info level
+func InfoFields(l Logger, msg string, fields ...Field) {
+	if fl, ok := l.(FieldLogger); ok {
+		fl.InfoFields(msg, fields...)
+		return
+	}
+
+	l.With(keysAndValues(fields)...).Info(msg)
+}
+
+// ErrorFields logs msg with fields at the error level
+func ErrorFields(l Logger, msg string, fields ...Field) {
+	if fl, ok := l.(FieldLogger); ok {
+		fl.ErrorFields(msg, fields...)
+		return
+	}
+
+	l.With(keysAndValues(fields)...).Error(msg)
+}
+
+// WithFields returns a Logger with the additional fields as context
+func WithFields(l Logger, fields ...Field) Logger {
+	if fl, ok := l.(FieldLogger); ok {
+		return fl.WithFields(fields...)
+	}
+
+	return l.With(keysAndValues(fields)...)
+}
+
+// FieldsToMap converts fields to a map of their keys and values.
+// Objects are converted to nested maps.
+func FieldsToMap(fields ...Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	return enc.Fields
+}
+
+// keysAndValues converts fields to loosely typed key-value pairs for Loggers that are not FieldLoggers
+func keysAndValues(fields []Field) []interface{} {
+	args := make([]interface{}, 0, len(fields)*2)
+
+	var enc *zapcore.MapObjectEncoder
+	for _, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			args = append(args, f.Key, f.String)
+			continue
+		case zapcore.Int64Type:
+			args = append(args, f.Key, f.Integer)
+			continue
+		case zapcore.BoolType:
+			args = append(args, f.Key, f.Integer == 1)
+			continue
+		case zapcore.DurationType:
+			args = append(args, f.Key, time.Duration(f.Integer))
+			continue
+		}
+
+		// convert one at a time to preserve the order of the fields, reusing the encoder
+		if enc == nil {
+			enc = zapcore.NewMapObjectEncoder()
+		}
+		f.AddTo(enc)
+		for k, v := range enc.Fields {
+			args = append(args, k, v)
+			delete(enc.Fields, k)
+		}
+	}
+
+	return args
+}
@@ -0,0 +1,161 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/viaduct-ai/vgo/log"
+	"github.com/viaduct-ai/vgo/testutils"
+)
+
+type testObject struct {
+	name string
+}
+
+func (o testObject) MarshalLogObject(enc log.ObjectEncoder) error {
+	enc.AddString("name", o.name)
+	return nil
+}
+
+// keyValueLogger only implements the loosely typed Logger interface
+type keyValueLogger struct {
+	*testutils.TestLogger
+}
+
+func (l keyValueLogger) With(args ...interface{}) log.Logger {
+	l.TestLogger.With(args...)
+	return l
+}
+
+func TestFieldsToMap(t *testing.T) {
+	t.Parallel()
+
+	err := errors.New("test")
+
+	got := log.FieldsToMap(
+		log.String("string", "test"),
+		log.Int("int", 1),
+		log.Int64("int64", 2),
+		log.Float64("float64", 1.5),
+		log.Bool("bool", true),
+		log.Err(err),
+		log.Duration("duration", time.Second),
+		log.Strings("strings", []string{"a", "b"}),
+		log.Object("object", testObject{name: "test"}),
+		log.Any("any", map[string]string{"key": "value"}),
+	)
+
+	want := map[string]interface{}{
+		"string":   "test",
+		"int":      int64(1),
+		"int64":    int64(2),
+		"float64":  1.5,
+		"bool":     true,
+		"error":    "test",
+		"duration": time.Second,
+		"strings":  []interface{}{"a", "b"},
+		"object":   map[string]interface{}{"name": "test"},
+		"any":      map[string]string{"key": "value"},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v. got %v", want, got)
+	}
+}
+
+func TestFieldLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	sink, err := log.WriterSink(&buf, log.JSONEncoding, zapcore.DebugLevel)
+	if err != nil {
+		t.Fatalf("error creating sink: %v", err)
+	}
+
+	logger := log.WithFields(log.NewTeeLogger(sink), log.String("service", "test"))
+
+	log.DebugFields(logger, "debug", log.Int("n", 1))
+	log.InfoFields(logger, "info", log.Object("object", testObject{name: "test"}))
+	log.ErrorFields(logger, "error", log.Err(errors.New("failed")))
+
+	entries := decodeLines(t, &buf)
+	if len(entries) != 3 {
+		t.Fatalf("want 3 entries. got %d", len(entries))
+	}
+
+	for _, entry := range entries {
+		if entry["service"] != "test" {
+			t.Errorf("want service context on %v", entry)
+		}
+	}
+
+	if entries[0]["n"] != float64(1) {
+		t.Errorf("want n field. got %v", entries[0])
+	}
+
+	if !reflect.DeepEqual(map[string]interface{}{"name": "test"}, entries[1]["object"]) {
+		t.Errorf("want nested object. got %v", entries[1])
+	}
+
+	if entries[2]["error"] != "failed" {
+		t.Errorf("want error field. got %v", entries[2])
+	}
+}
+
+func TestFieldsFallback(t *testing.T) {
+	t.Parallel()
+
+	logger := keyValueLogger{testutils.NewTestLogger()}
+
+	log.InfoFields(logger, "info", log.String("key", "value"), log.Int("n", 1))
+
+	if !reflect.DeepEqual([]interface{}{"info"}, logger.InfoLogs) {
+		t.Errorf("want info logs %v. got %v", []interface{}{"info"}, logger.InfoLogs)
+	}
+
+	wantContext := map[string]interface{}{
+		"key": "value",
+		"n":   int64(1),
+	}
+
+	if !reflect.DeepEqual(wantContext, logger.Context) {
+		t.Errorf("want context %v. got %v", wantContext, logger.Context)
+	}
+}
+
+func BenchmarkInfoFields(b *testing.B) {
+	sink, err := log.WriterSink(ioutil.Discard, log.JSONEncoding, zapcore.InfoLevel)
+	if err != nil {
+		b.Fatalf("error creating sink: %v", err)
+	}
+
+	logger := log.NewTeeLogger(sink)
+
+	b.Run("Fields", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			log.InfoFields(logger, "request",
+				log.String("method", "GET"),
+				log.String("endpoint", "/v1/test"),
+				log.Int("status", 200),
+			)
+		}
+	})
+
+	b.Run("KeysAndValues", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			logger.With(
+				"method", "GET",
+				"endpoint", "/v1/test",
+				"status", 200,
+			).Info("request")
+		}
+	})
+}
@@ -0,0 +1,229 @@
+package log
+
+import (
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Encoding is the format a Sink uses to encode log entries
+type Encoding string
+
+const (
+	// JSONEncoding encodes entries as one JSON object per line
+	JSONEncoding Encoding = "json"
+	// ConsoleEncoding encodes entries as human readable, tab separated lines
+	ConsoleEncoding Encoding = "console"
+)
+
+// Sink is a single destination of a tee Logger.
+// A Sink either wraps an existing Logger or a zapcore.Core.
+type Sink struct {
+	core   zapcore.Core
+	logger Logger
+	level  zapcore.Level
+}
+
+// LoggerSink returns a Sink that forwards entries at or above level to l
+func LoggerSink(l Logger, level zapcore.Level) Sink {
+	return Sink{
+		logger: l,
+		level:  level,
+	}
+}
+
+// CoreSink returns a Sink that writes entries to core.
+// The core decides which levels it accepts and how entries are encoded.
+func CoreSink(core zapcore.Core) Sink {
+	return Sink{
+		core: core,
+	}
+}
+
+// WriterSink returns a Sink that encodes entries at or above level with enc and writes them to w
+func WriterSink(w io.Writer, enc Encoding, level zapcore.Level) (Sink, error) {
+	var encoder zapcore.Encoder
+
+	switch enc {
+	case JSONEncoding:
+		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	case ConsoleEncoding:
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	default:
+		return Sink{}, fmt.Errorf("unknown log encoding %q", enc)
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.AddSync(w), level)
+	return CoreSink(core), nil
+}
+
+// FileConfig configures a log file that is rotated by size and age
+type FileConfig struct {
+	// Filename is the file to write logs to. Backups are kept in the same directory.
+	Filename string
+	// MaxSizeMB is the maximum size of the file before it is rotated. Defaults to 100MB.
+	MaxSizeMB int
+	// MaxAgeDays is the maximum number of days to retain rotated files. Zero keeps them forever.
+	MaxAgeDays int
+	// MaxBackups is the maximum number of rotated files to retain. Zero keeps all of them.
+	MaxBackups int
+	// Compress gzips rotated files
+	Compress bool
+}
+
+// NewRotatingFile returns a writer that appends to cfg.Filename and rotates it by size and age.
+// The caller is responsible for closing it.
+func NewRotatingFile(cfg FileConfig) io.WriteCloser {
+	return &lumberjack.Logger{
+		Filename:   cfg.Filename,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxAge:     cfg.MaxAgeDays,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+	}
+}
+
+type leveledLogger struct {
+	Logger
+	level zapcore.Level
+}
+
+type teeLogger struct {
+	loggers []leveledLogger
+}
+
+// NewTeeLogger creates a Logger that writes every entry to each of the sinks.
+// zapcore backed sinks are combined into a single zap logger.
+func NewTeeLogger(sinks ...Sink) Logger {
+	var cores []zapcore.Core
+	var loggers []leveledLogger
+
+	for _, s := range sinks {
+		if s.core != nil {
+			cores = append(cores, s.core)
+			continue
+		}
+
+		if s.logger != nil {
+			loggers = append(loggers, leveledLogger{Logger: s.logger, level: s.level})
+		}
+	}
+
+	if len(cores) > 0 {
+		z := newZapLogger(zap.New(zapcore.NewTee(cores...)))
+		// the cores filter their own levels
+		loggers = append([]leveledLogger{{Logger: z, level: zapcore.DebugLevel}}, loggers...)
+	}
+
+	return &teeLogger{
+		loggers: loggers,
+	}
+}
+
+// Error logs a message at the error level to every sink accepting it
+func (l *teeLogger) Error(args ...interface{}) {
+	for _, s := range l.loggers {
+		if s.level.Enabled(zapcore.ErrorLevel) {
+			s.Error(args...)
+		}
+	}
+}
+
+// Info logs a message at the info level to every sink accepting it
+func (l *teeLogger) Info(args ...interface{}) {
+	for _, s := range l.loggers {
+		if s.level.Enabled(zapcore.InfoLevel) {
+			s.Info(args...)
+		}
+	}
+}
+
+// Debug logs a message at the debug level to every sink accepting it
+func (l *teeLogger) Debug(args ...interface{}) {
+	for _, s := range l.loggers {
+		if s.level.Enabled(zapcore.DebugLevel) {
+			s.Debug(args...)
+		}
+	}
+}
+
+// Errorf logs a message at the error level to every sink accepting it
+func (l *teeLogger) Errorf(template string, args ...interface{}) {
+	for _, s := range l.loggers {
+		if s.level.Enabled(zapcore.ErrorLevel) {
+			s.Errorf(template, args...)
+		}
+	}
+}
+
+// Infof logs a message at the info level to every sink accepting it
+func (l *teeLogger) Infof(template string, args ...interface{}) {
+	for _, s := range l.loggers {
+		if s.level.Enabled(zapcore.InfoLevel) {
+			s.Infof(template, args...)
+		}
+	}
+}
+
+// Debugf logs a message at the debug level to every sink accepting it
+func (l *teeLogger) Debugf(template string, args ...interface{}) {
+	for _, s := range l.loggers {
+		if s.level.Enabled(zapcore.DebugLevel) {
+			s.Debugf(template, args...)
+		}
+	}
+}
+
+// ErrorFields logs a message with typed fields at the error level to every sink accepting it
+func (l *teeLogger) ErrorFields(msg string, fields ...Field) {
+	for _, s := range l.loggers {
+		if s.level.Enabled(zapcore.ErrorLevel) {
+			ErrorFields(s.Logger, msg, fields...)
+		}
+	}
+}
+
+// InfoFields logs a message with typed fields at the info level to every sink accepting it
+func (l *teeLogger) InfoFields(msg string, fields ...Field) {
+	for _, s := range l.loggers {
+		if s.level.Enabled(zapcore.InfoLevel) {
+			InfoFields(s.Logger, msg, fields...)
+		}
+	}
+}
+
+// DebugFields logs a message with typed fields at the debug level to every sink accepting it
+func (l *teeLogger) DebugFields(msg string, fields ...Field) {
+	for _, s := range l.loggers {
+		if s.level.Enabled(zapcore.DebugLevel) {
+			DebugFields(s.Logger, msg, fields...)
+		}
+	}
+}
+
+// With returns a new tee Logger with the additional args as key-value context on every sink
+func (l *teeLogger) With(args ...interface{}) Logger {
+	loggers := make([]leveledLogger, len(l.loggers))
+	for i, s := range l.loggers {
+		loggers[i] = leveledLogger{Logger: s.With(args...), level: s.level}
+	}
+
+	return &teeLogger{
+		loggers: loggers,
+	}
+}
+
+// WithFields returns a new tee Logger with the additional typed fields as context on every sink
+func (l *teeLogger) WithFields(fields ...Field) Logger {
+	loggers := make([]leveledLogger, len(l.loggers))
+	for i, s := range l.loggers {
+		loggers[i] = leveledLogger{Logger: WithFields(s.Logger, fields...), level: s.level}
+	}
+
+	return &teeLogger{
+		loggers: loggers,
+	}
+}
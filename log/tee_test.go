@@ -0,0 +1,133 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/viaduct-ai/vgo/log"
+	"github.com/viaduct-ai/vgo/testutils"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	entries := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("error decoding log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestTeeLogger(t *testing.T) {
+	t.Parallel()
+
+	var stdout, file bytes.Buffer
+
+	stdoutSink, err := log.WriterSink(&stdout, log.JSONEncoding, zapcore.InfoLevel)
+	if err != nil {
+		t.Fatalf("error creating stdout sink: %v", err)
+	}
+
+	fileSink, err := log.WriterSink(&file, log.JSONEncoding, zapcore.WarnLevel)
+	if err != nil {
+		t.Fatalf("error creating file sink: %v", err)
+	}
+
+	hook := testutils.NewTestLogger()
+
+	logger := log.NewTeeLogger(stdoutSink, fileSink, log.LoggerSink(hook, zapcore.ErrorLevel))
+	logger = logger.With("service", "test")
+
+	logger.Debug("debug")
+	logger.Infof("%s", "info")
+	logger.Error("error")
+
+	wantStdout := []string{"info", "error"}
+	gotStdout := []string{}
+	for _, entry := range decodeLines(t, &stdout) {
+		gotStdout = append(gotStdout, entry["msg"].(string))
+
+		if entry["service"] != "test" {
+			t.Errorf("want service context on %v", entry)
+		}
+	}
+
+	if !reflect.DeepEqual(wantStdout, gotStdout) {
+		t.Errorf("want stdout messages %v. got %v", wantStdout, gotStdout)
+	}
+
+	fileEntries := decodeLines(t, &file)
+	if len(fileEntries) != 1 || fileEntries[0]["msg"] != "error" {
+		t.Errorf("want only the error entry in file sink. got %v", fileEntries)
+	}
+
+	if !reflect.DeepEqual([]interface{}{"error"}, hook.ErrorLogs) {
+		t.Errorf("want hook error logs %v. got %v", []interface{}{"error"}, hook.ErrorLogs)
+	}
+
+	if len(hook.InfoLogs) != 0 || len(hook.DebugLogs) != 0 {
+		t.Errorf("want hook to only receive errors. got info %v, debug %v", hook.InfoLogs, hook.DebugLogs)
+	}
+
+	if hook.Context["service"] != "test" {
+		t.Errorf("want hook context %v. got %v", map[string]interface{}{"service": "test"}, hook.Context)
+	}
+}
+
+func TestWriterSinkUnknownEncoding(t *testing.T) {
+	t.Parallel()
+
+	if _, err := log.WriterSink(ioutil.Discard, log.Encoding("xml"), zapcore.InfoLevel); err == nil {
+		t.Errorf("want error for unknown encoding")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "vgo-log")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	file := log.NewRotatingFile(log.FileConfig{Filename: filename, MaxSizeMB: 1})
+
+	sink, err := log.WriterSink(file, log.ConsoleEncoding, zapcore.InfoLevel)
+	if err != nil {
+		t.Fatalf("error creating file sink: %v", err)
+	}
+
+	log.NewTeeLogger(sink).Info("written")
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("error closing file: %v", err)
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("error reading log file: %v", err)
+	}
+
+	if !strings.Contains(string(content), "written") {
+		t.Errorf("want log file to contain %q. got %q", "written", content)
+	}
+}
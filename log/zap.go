@@ -6,21 +6,29 @@ import (
 
 type zapLogger struct {
 	z *zap.SugaredLogger
+	l *zap.Logger
 }
 
 // NewZapLogger creates a custom zap.SugaredLogger implementation of the Logger interface
 func NewZapLogger(config zap.Config, opts ...zap.Option) (Logger, error) {
 	logger, err := config.Build(opts...)
-	return &zapLogger{
-		z: logger.Sugar(),
-	}, err
+	return newZapLogger(logger), err
 }
 
 // NewNoOpZapLogger creates a NoOp Logger for testing purposes
 func NewNoOpZapLogger() Logger {
 	logger := zap.NewNop()
+	return newZapLogger(logger)
+}
+
+func newZapLogger(logger *zap.Logger) *zapLogger {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
 	return &zapLogger{
 		z: logger.Sugar(),
+		l: logger,
 	}
 }
 
@@ -54,10 +62,31 @@ func (l *zapLogger) Debugf(template string, args ...interface{}) {
 	l.z.Debugf(template, args...)
 }
 
+// ErrorFields logs a message with typed fields at the error level
+func (l *zapLogger) ErrorFields(msg string, fields ...Field) {
+	l.l.Error(msg, fields...)
+}
+
+// InfoFields logs a message with typed fields at the info level
+func (l *zapLogger) InfoFields(msg string, fields ...Field) {
+	l.l.Info(msg, fields...)
+}
+
+// DebugFields logs a message with typed fields at the debug level
+func (l *zapLogger) DebugFields(msg string, fields ...Field) {
+	l.l.Debug(msg, fields...)
+}
+
 // With returns a new a new zap.SugaredLogger Logger with the additional args as key-value context
 func (l *zapLogger) With(args ...interface{}) Logger {
 	logger := l.z.With(args...)
 	return &zapLogger{
 		z: logger,
+		l: logger.Desugar(),
 	}
 }
+
+// WithFields returns a new zap Logger with the additional typed fields as context
+func (l *zapLogger) WithFields(fields ...Field) Logger {
+	return newZapLogger(l.l.With(fields...))
+}
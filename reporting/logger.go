@@ -0,0 +1,163 @@
+package reporting
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/viaduct-ai/vgo/log"
+)
+
+// reportedKey is the key of the Reported field
+const reportedKey = "reported"
+
+// Reported returns a Field marking an error as already reported, for example by a recovery middleware
+// reporting panics itself. Loggers returned by NewLogger log such entries without reporting them again.
+func Reported() log.Field {
+	return log.Bool(reportedKey, true)
+}
+
+// isReported reports whether fields contain the Reported field
+func isReported(fields []log.Field) bool {
+	for _, f := range fields {
+		if f.Key == reportedKey && f.Type == zapcore.BoolType && f.Integer == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+type reportingLogger struct {
+	log.Logger
+	reporter Reporter
+	context  map[string]interface{}
+}
+
+// NewLogger returns a log.Logger that logs to l and also reports every Error and Errorf entry to r.
+// Context added with With and WithFields is attached to reported Events.
+func NewLogger(l log.Logger, r Reporter) log.Logger {
+	return &reportingLogger{
+		Logger:   l,
+		reporter: r,
+		context:  map[string]interface{}{},
+	}
+}
+
+// Error logs a message at the error level and reports it
+func (l *reportingLogger) Error(args ...interface{}) {
+	l.Logger.Error(args...)
+	l.report(fmt.Sprint(args...), args)
+}
+
+// Errorf logs a message at the error level and reports it
+func (l *reportingLogger) Errorf(template string, args ...interface{}) {
+	l.Logger.Errorf(template, args...)
+	l.report(fmt.Sprintf(template, args...), args)
+}
+
+// ErrorFields logs a message with typed fields at the error level and reports it,
+// unless fields contain the Reported field
+func (l *reportingLogger) ErrorFields(msg string, fields ...log.Field) {
+	log.ErrorFields(l.Logger, msg, fields...)
+
+	if isReported(fields) {
+		return
+	}
+
+	var args []interface{}
+	for _, f := range fields {
+		if f.Type == zapcore.ErrorType {
+			args = append(args, f.Interface)
+		}
+	}
+
+	l.withFields(fields).report(msg, args)
+}
+
+// InfoFields logs a message with typed fields at the info level
+func (l *reportingLogger) InfoFields(msg string, fields ...log.Field) {
+	log.InfoFields(l.Logger, msg, fields...)
+}
+
+// DebugFields logs a message with typed fields at the debug level
+func (l *reportingLogger) DebugFields(msg string, fields ...log.Field) {
+	log.DebugFields(l.Logger, msg, fields...)
+}
+
+// WithFields returns a new reporting Logger with the additional typed fields as context
+func (l *reportingLogger) WithFields(fields ...log.Field) log.Logger {
+	logger := l.withFields(fields)
+	logger.Logger = log.WithFields(l.Logger, fields...)
+
+	return logger
+}
+
+// withFields returns a copy of l with fields added to the reported context only
+func (l *reportingLogger) withFields(fields []log.Field) *reportingLogger {
+	context := make(map[string]interface{}, len(l.context)+len(fields))
+	for k, v := range l.context {
+		context[k] = v
+	}
+
+	for k, v := range log.FieldsToMap(fields...) {
+		context[k] = v
+	}
+
+	return &reportingLogger{
+		Logger:   l.Logger,
+		reporter: l.reporter,
+		context:  context,
+	}
+}
+
+// With returns a new reporting Logger with the additional args as context. Like zap's sugared loggers,
+// args are key-value pairs or typed log.Fields.
+func (l *reportingLogger) With(args ...interface{}) log.Logger {
+	var fields []log.Field
+	keysAndValues := make([]interface{}, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if f, ok := args[i].(log.Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+
+		keysAndValues = append(keysAndValues, args[i])
+		if i+1 < len(args) {
+			keysAndValues = append(keysAndValues, args[i+1])
+			i++
+		}
+	}
+
+	logger := l.withFields(fields)
+	for i := 1; i < len(keysAndValues); i += 2 {
+		if key, ok := keysAndValues[i-1].(string); ok {
+			logger.context[key] = keysAndValues[i]
+		}
+	}
+
+	logger.Logger = l.Logger.With(keysAndValues...)
+	if len(fields) > 0 {
+		logger.Logger = log.WithFields(logger.Logger, fields...)
+	}
+
+	return logger
+}
+
+// report sends msg to the reporter. The first error in args, if any, determines the error type.
+// The stack starts at the first caller outside of the log and reporting packages.
+func (l *reportingLogger) report(msg string, args []interface{}) {
+	var err error
+	for _, arg := range args {
+		if e, ok := arg.(error); ok {
+			err = e
+			break
+		}
+	}
+
+	e := NewEvent(LevelError, msg, err, 2)
+	if len(l.context) > 0 {
+		e.Context = l.context
+	}
+
+	l.reporter.Report(e)
+}
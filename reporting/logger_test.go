@@ -0,0 +1,130 @@
+package reporting_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/viaduct-ai/vgo/log"
+	"github.com/viaduct-ai/vgo/reporting"
+	"github.com/viaduct-ai/vgo/testutils"
+)
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	base := testutils.NewTestLogger()
+	memory := reporting.NewMemoryReporter()
+
+	logger := reporting.NewLogger(base, memory).With("request_id", "abc")
+
+	logger.Info("info")
+	logger.Error("failed: ", errors.New("test"))
+	logger.Errorf("failed %d times", 2)
+
+	if !reflect.DeepEqual([]interface{}{"failed: ", errors.New("test"), "failed 2 times"}, base.ErrorLogs) {
+		t.Errorf("want errors to be logged. got %v", base.ErrorLogs)
+	}
+
+	events := memory.Events()
+	if len(events) != 2 {
+		t.Fatalf("want 2 events. got %d: %+v", len(events), events)
+	}
+
+	if events[0].Message != "failed: test" || events[0].ErrorType != "*errors.errorString" {
+		t.Errorf("want error event. got %+v", events[0])
+	}
+
+	if events[1].Message != "failed 2 times" || events[1].ErrorType != "" {
+		t.Errorf("want message event. got %+v", events[1])
+	}
+
+	for _, e := range events {
+		if e.Context["request_id"] != "abc" {
+			t.Errorf("want request_id context. got %v", e.Context)
+		}
+
+		if len(e.Stack) == 0 || !strings.HasSuffix(e.Stack[0].Function, "TestLogger") {
+			t.Errorf("want stack to start at the logging call. got %+v", e.Stack)
+		}
+	}
+}
+
+func TestLoggerFields(t *testing.T) {
+	t.Parallel()
+
+	memory := reporting.NewMemoryReporter()
+	logger := log.WithFields(reporting.NewLogger(testutils.NewTestLogger(), memory), log.String("request_id", "abc"))
+
+	log.InfoFields(logger, "info")
+	log.ErrorFields(logger, "failed", log.Err(errors.New("test")))
+
+	events := memory.Events()
+	if len(events) != 1 {
+		t.Fatalf("want 1 event. got %d: %+v", len(events), events)
+	}
+
+	if events[0].Message != "failed" || events[0].ErrorType != "*errors.errorString" {
+		t.Errorf("want error event. got %+v", events[0])
+	}
+
+	if events[0].Context["request_id"] != "abc" || events[0].Context["error"] != "test" {
+		t.Errorf("want field context. got %v", events[0].Context)
+	}
+}
+
+func TestLoggerWithFields(t *testing.T) {
+	t.Parallel()
+
+	memory := reporting.NewMemoryReporter()
+	base := testutils.NewTestLogger()
+	logger := reporting.NewLogger(base, memory).With("user", "alice", log.String("request_id", "abc"), "attempt", 2)
+
+	logger.Error("failed")
+
+	events := memory.Events()
+	if len(events) != 1 {
+		t.Fatalf("want 1 event. got %d: %+v", len(events), events)
+	}
+
+	want := map[string]interface{}{"user": "alice", "request_id": "abc", "attempt": 2}
+	if !reflect.DeepEqual(events[0].Context, want) {
+		t.Errorf("want context %v. got %v", want, events[0].Context)
+	}
+
+	// the fields are logged too
+	if base.Context["request_id"] != "abc" || base.Context["attempt"] != 2 {
+		t.Errorf("want fields to be logged. got %v", base.Context)
+	}
+}
+
+func TestLoggerStackStartsAtCaller(t *testing.T) {
+	t.Parallel()
+
+	memory := reporting.NewMemoryReporter()
+	reporter := reporting.NewLogger(testutils.NewTestLogger(), memory)
+	tee := log.NewTeeLogger(log.LoggerSink(reporter, zapcore.ErrorLevel))
+
+	log.ErrorFields(reporter, "failed", log.Err(errors.New("test")))
+	tee.Error("failed")
+	log.ErrorFields(tee, "failed", log.Err(errors.New("test")))
+
+	events := memory.Events()
+	if len(events) != 3 {
+		t.Fatalf("want 3 events. got %d: %+v", len(events), events)
+	}
+
+	for _, e := range events {
+		if len(e.Stack) == 0 || !strings.HasSuffix(e.Stack[0].Function, "reporting_test.TestLoggerStackStartsAtCaller") {
+			t.Errorf("want stack to start at the calling function. got %+v", e.Stack)
+		}
+	}
+
+	// the same error logged from different lines is not grouped
+	if events[0].Fingerprint == events[2].Fingerprint {
+		t.Errorf("want fingerprints of different calls to differ. got %s", events[0].Fingerprint)
+	}
+}
@@ -0,0 +1,101 @@
+package reporting
+
+import (
+	"sync"
+	"time"
+)
+
+// maxGroups bounds the number of fingerprints tracked
+const maxGroups = 1024
+
+type group struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+type rateLimitedReporter struct {
+	next   Reporter
+	limit  int
+	window time.Duration
+
+	mu     sync.Mutex
+	groups map[string]*group
+}
+
+// NewRateLimitedReporter groups Events by fingerprint and forwards at most limit Events
+// of each group to next per window. The number of dropped Events is carried on the
+// next forwarded Event of the group as Suppressed.
+func NewRateLimitedReporter(next Reporter, limit int, window time.Duration) Reporter {
+	return &rateLimitedReporter{
+		next:   next,
+		limit:  limit,
+		window: window,
+		groups: map[string]*group{},
+	}
+}
+
+// Report forwards e unless its group has exceeded the limit for the current window
+func (r *rateLimitedReporter) Report(e Event) {
+	now := time.Now()
+	key := e.Fingerprint
+	if key == "" {
+		key = Fingerprint(e)
+	}
+
+	r.mu.Lock()
+
+	g, ok := r.groups[key]
+	if !ok {
+		if len(r.groups) >= maxGroups {
+			r.prune(now)
+		}
+
+		g = &group{start: now}
+		r.groups[key] = g
+	}
+
+	if now.Sub(g.start) >= r.window {
+		// keep the suppressed count so it is reported with the next event
+		g.start = now
+		g.count = 0
+	}
+
+	if g.count >= r.limit {
+		g.suppressed++
+		r.mu.Unlock()
+		return
+	}
+
+	g.count++
+	e.Suppressed = g.suppressed
+	g.suppressed = 0
+
+	r.mu.Unlock()
+
+	r.next.Report(e)
+}
+
+// prune drops groups whose window has expired without suppressed events. If none has, the group with
+// the oldest window is dropped instead, losing its suppressed count, so at most maxGroups are tracked.
+// r.mu must be held.
+func (r *rateLimitedReporter) prune(now time.Time) {
+	var (
+		oldestKey string
+		oldest    *group
+	)
+	for key, g := range r.groups {
+		if g.suppressed == 0 && now.Sub(g.start) >= r.window {
+			delete(r.groups, key)
+			continue
+		}
+
+		if oldest == nil || g.start.Before(oldest.start) {
+			oldestKey, oldest = key, g
+		}
+	}
+
+	if len(r.groups) >= maxGroups {
+		delete(r.groups, oldestKey)
+	}
+}
@@ -0,0 +1,77 @@
+package reporting_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/viaduct-ai/vgo/reporting"
+)
+
+func TestRateLimitedReporter(t *testing.T) {
+	t.Parallel()
+
+	window := 50 * time.Millisecond
+	memory := reporting.NewMemoryReporter()
+	reporter := reporting.NewRateLimitedReporter(memory, 2, window)
+
+	grouped := reporting.Event{Message: "grouped", Fingerprint: "a"}
+	other := reporting.Event{Message: "other", Fingerprint: "b"}
+
+	for i := 0; i < 5; i++ {
+		reporter.Report(grouped)
+	}
+	reporter.Report(other)
+
+	events := memory.Events()
+	if len(events) != 3 {
+		t.Fatalf("want 3 events. got %d: %+v", len(events), events)
+	}
+
+	if events[2].Message != "other" {
+		t.Errorf("want other group to be reported. got %+v", events[2])
+	}
+
+	time.Sleep(window)
+	reporter.Report(grouped)
+
+	events = memory.Events()
+	if len(events) != 4 {
+		t.Fatalf("want 4 events after the window. got %d", len(events))
+	}
+
+	if events[3].Suppressed != 3 {
+		t.Errorf("want 3 suppressed events. got %d", events[3].Suppressed)
+	}
+}
+
+func TestRateLimitedReporterEvictsOldestGroup(t *testing.T) {
+	t.Parallel()
+
+	memory := reporting.NewMemoryReporter()
+	reporter := reporting.NewRateLimitedReporter(memory, 1, time.Hour)
+
+	oldest := reporting.Event{Message: "oldest", Fingerprint: "oldest"}
+	reporter.Report(oldest)
+	reporter.Report(oldest)
+
+	// so no other group starts at the same time on coarse clocks
+	time.Sleep(time.Millisecond)
+
+	// fill the 1024 tracked groups, none of which expires
+	for i := 0; i < 1024; i++ {
+		reporter.Report(reporting.Event{Message: "other", Fingerprint: strconv.Itoa(i)})
+	}
+
+	// the oldest group was evicted to track the others, so its limit starts over
+	reporter.Report(oldest)
+
+	events := memory.Events()
+	if len(events) != 1026 {
+		t.Fatalf("want 1026 events. got %d", len(events))
+	}
+
+	if last := events[len(events)-1]; last.Message != "oldest" {
+		t.Errorf("want the evicted group to be reported again. got %+v", last)
+	}
+}
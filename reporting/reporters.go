@@ -0,0 +1,68 @@
+package reporting
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// MemoryReporter keeps reported Events in memory.
+// It is intended for tests and local development.
+type MemoryReporter struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewMemoryReporter returns a reference to a new MemoryReporter
+func NewMemoryReporter() *MemoryReporter {
+	return &MemoryReporter{
+		events: []Event{},
+	}
+}
+
+// Report stores e
+func (r *MemoryReporter) Report(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, e)
+}
+
+// Events returns a copy of every Event reported so far
+func (r *MemoryReporter) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]Event, len(r.events))
+	copy(events, r.events)
+	return events
+}
+
+// Reset drops all stored Events
+func (r *MemoryReporter) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = []Event{}
+}
+
+// WriterReporter writes each Event as a line of JSON to an io.Writer, such as a local file
+type WriterReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriterReporter returns a reference to a new WriterReporter writing to w
+func NewWriterReporter(w io.Writer) *WriterReporter {
+	return &WriterReporter{
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Report writes e to the underlying writer. Write errors are ignored.
+func (r *WriterReporter) Report(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.enc.Encode(e)
+}
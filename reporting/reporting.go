@@ -0,0 +1,231 @@
+// Package reporting sends errors and recovered panics to an error tracker.
+//
+// The Reporter interface is vendor neutral, so a Sentry (or similar) client
+// only needs a thin adapter. MemoryReporter and WriterReporter are provided
+// for local development and tests.
+package reporting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/viaduct-ai/vgo/jwtutils"
+)
+
+// Level is the severity of an Event
+type Level string
+
+const (
+	// LevelError is used for errors logged by the application
+	LevelError Level = "error"
+	// LevelPanic is used for recovered panics
+	LevelPanic Level = "panic"
+)
+
+// redactedHeaders are never attached to an Event
+var redactedHeaders = map[string]bool{
+	http.CanonicalHeaderKey("Authorization"): true,
+	http.CanonicalHeaderKey("Cookie"):        true,
+	http.CanonicalHeaderKey("Set-Cookie"):    true,
+}
+
+// Reporter sends Events to an error tracker.
+// Implementations must be safe for concurrent use and should not block the caller for long.
+type Reporter interface {
+	Report(e Event)
+}
+
+// Frame is a single frame of a stack trace
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Request is the request metadata attached to an Event
+type Request struct {
+	Method     string            `json:"method"`
+	URL        string            `json:"url"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+}
+
+// User is the subject the Event happened for
+type User struct {
+	ID string `json:"id"`
+}
+
+// Event is a single error report
+type Event struct {
+	Timestamp   time.Time              `json:"timestamp"`
+	Level       Level                  `json:"level"`
+	Message     string                 `json:"message"`
+	ErrorType   string                 `json:"error_type,omitempty"`
+	Stack       []Frame                `json:"stack,omitempty"`
+	Request     *Request               `json:"request,omitempty"`
+	User        *User                  `json:"user,omitempty"`
+	Context     map[string]interface{} `json:"context,omitempty"`
+	Fingerprint string                 `json:"fingerprint"`
+	// Suppressed is the number of events with the same fingerprint dropped by rate limiting since the last reported one
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// internalPackages are the packages whose frames are dropped from the top of Event stacks,
+// so events logged through a Logger start at the code logging them
+var internalPackages = []string{
+	"github.com/viaduct-ai/vgo/log.",
+	"github.com/viaduct-ai/vgo/reporting.",
+}
+
+// NewEvent creates a fingerprinted Event at level with a stack trace of the caller.
+// If msg is empty, the message of err is used instead. err may be nil.
+// skip is the number of additional stack frames to skip, 0 being the caller of NewEvent.
+// Frames of the log and reporting packages at the top of the stack are skipped too.
+func NewEvent(level Level, msg string, err error, skip int) Event {
+	e := Event{
+		Timestamp: time.Now(),
+		Level:     level,
+		Message:   msg,
+		Stack:     trimInternalFrames(CaptureStack(skip + 1)),
+	}
+
+	if err != nil {
+		e.ErrorType = fmt.Sprintf("%T", err)
+
+		if e.Message == "" {
+			e.Message = err.Error()
+		}
+	}
+
+	e.Fingerprint = Fingerprint(e)
+
+	return e
+}
+
+// NewPanicEvent creates an Event for a value returned by recover.
+// It must be called from the deferred function that recovered the panic, so
+// the stack starts at the function that panicked.
+func NewPanicEvent(recovered interface{}) Event {
+	err, ok := recovered.(error)
+	if !ok {
+		err = fmt.Errorf("%v", recovered)
+	}
+
+	e := NewEvent(LevelPanic, fmt.Sprintf("panic: %v", recovered), err, 1)
+
+	// drop the deferred function and the runtime frames raising the panic
+	for i, frame := range e.Stack {
+		if frame.Function == "runtime.gopanic" {
+			e.Stack = e.Stack[i+1:]
+			break
+		}
+	}
+
+	for len(e.Stack) > 0 && strings.HasPrefix(e.Stack[0].Function, "runtime.") {
+		e.Stack = e.Stack[1:]
+	}
+	e.Fingerprint = Fingerprint(e)
+
+	return e
+}
+
+// WithRequest attaches request metadata and the JWT subject of r, if any, to the Event
+func (e Event) WithRequest(r *http.Request) Event {
+	if r == nil {
+		return e
+	}
+
+	headers := make(map[string]string, len(r.Header))
+	for k, v := range r.Header {
+		if redactedHeaders[k] {
+			continue
+		}
+		headers[k] = strings.Join(v, ", ")
+	}
+
+	e.Request = &Request{
+		Method:     r.Method,
+		URL:        r.URL.String(),
+		Headers:    headers,
+		RemoteAddr: r.RemoteAddr,
+	}
+
+	// try parse claims from request, ignore errors
+	claims, _ := jwtutils.ParseUnverifiedTokenClaimsFromRequest(r)
+	if sub, ok := claims["sub"].(string); ok && sub != "" {
+		e.User = &User{ID: sub}
+	}
+
+	return e
+}
+
+// CaptureStack returns the stack of the calling goroutine.
+// skip is the number of frames to skip, 0 being the caller of CaptureStack.
+func CaptureStack(skip int) []Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+
+	stack := []Frame{}
+	if n == 0 {
+		return stack
+	}
+
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, Frame{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		})
+
+		if !more {
+			break
+		}
+	}
+
+	return stack
+}
+
+// trimInternalFrames drops the frames of internalPackages from the top of stack,
+// unless the whole stack is internal
+func trimInternalFrames(stack []Frame) []Frame {
+	for i, frame := range stack {
+		if !isInternal(frame.Function) {
+			return stack[i:]
+		}
+	}
+	return stack
+}
+
+func isInternal(function string) bool {
+	for _, pkg := range internalPackages {
+		if strings.HasPrefix(function, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fingerprint groups events with the same error type raised from the same place.
+// Messages are ignored unless there is no error type, as they often contain request specific values.
+func Fingerprint(e Event) string {
+	h := sha256.New()
+
+	if e.ErrorType != "" {
+		h.Write([]byte(e.ErrorType))
+	} else {
+		h.Write([]byte(e.Message))
+	}
+
+	if len(e.Stack) > 0 {
+		fmt.Fprintf(h, "|%s:%d", e.Stack[0].Function, e.Stack[0].Line)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
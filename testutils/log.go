@@ -6,7 +6,7 @@ import (
 	"github.com/viaduct-ai/vgo/log"
 )
 
-// TestLogger implements the log.Logger interface
+// TestLogger implements the log.Logger and log.FieldLogger interfaces
 // plus additional public fields for accessing what has been "written" to output.
 // Debug, Info, and Error all have their own "output buffers".
 type TestLogger struct {
@@ -77,3 +77,30 @@ func (l *TestLogger) With(args ...interface{}) log.Logger {
 
 	return l
 }
+
+// ErrorFields logs msg to the ErrorLogs slice and adds fields to the Context
+func (l *TestLogger) ErrorFields(msg string, fields ...log.Field) {
+	l.WithFields(fields...)
+	l.ErrorLogs = append(l.ErrorLogs, msg)
+}
+
+// InfoFields logs msg to the InfoLogs slice and adds fields to the Context
+func (l *TestLogger) InfoFields(msg string, fields ...log.Field) {
+	l.WithFields(fields...)
+	l.InfoLogs = append(l.InfoLogs, msg)
+}
+
+// DebugFields logs msg to the DebugLogs slice and adds fields to the Context
+func (l *TestLogger) DebugFields(msg string, fields ...log.Field) {
+	l.WithFields(fields...)
+	l.DebugLogs = append(l.DebugLogs, msg)
+}
+
+// WithFields mutates the existing TestLogger to include the additional fields in the Context and returns it.
+func (l *TestLogger) WithFields(fields ...log.Field) log.Logger {
+	for k, v := range log.FieldsToMap(fields...) {
+		l.Context[k] = v
+	}
+
+	return l
+}